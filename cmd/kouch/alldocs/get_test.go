@@ -22,6 +22,13 @@ func TestGetAllDocsOpts(t *testing.T) {
 			Options: &chttp.Options{},
 		},
 	})
+	tests.Add("partition", test.OptionsTest{
+		Args: []string{"foo", "--" + kouch.FlagPartition, "bar"},
+		Expected: &kouch.Options{
+			Target:  &kouch.Target{Database: "foo", Partition: "bar"},
+			Options: &chttp.Options{},
+		},
+	})
 	tests.Add("conflicts", test.OptionsTest{
 		Args: []string{"--" + kouch.FlagConflicts},
 		Expected: &kouch.Options{
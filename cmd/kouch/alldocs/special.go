@@ -0,0 +1,74 @@
+package alldocs
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getDesignDocsCmd)
+	registry.Register([]string{"get"}, getLocalDocsCmd)
+}
+
+func getDesignDocsCmd() *cobra.Command {
+	cmd := specialDocsCmd("_design_docs")
+	cmd.Use = "design-docs [target]"
+	cmd.Aliases = []string{"ddocs"}
+	cmd.Short = "Fetches the design documents in a database."
+	cmd.Long = "Fetches the design documents in a database, subject to the same restrictions as 'kouch get alldocs'.\n\n" +
+		kouch.TargetHelpText(kouch.TargetDatabase)
+	return cmd
+}
+
+func getLocalDocsCmd() *cobra.Command {
+	cmd := specialDocsCmd("_local_docs")
+	cmd.Use = "local-docs [target]"
+	cmd.Aliases = []string{"localdocs"}
+	cmd.Short = "Fetches the local (non-replicating) documents in a database."
+	cmd.Long = "Fetches the local documents in a database, such as replication checkpoints, subject to the same restrictions as 'kouch get alldocs'.\n\n" +
+		kouch.TargetHelpText(kouch.TargetDatabase)
+	return cmd
+}
+
+// specialDocsCmd returns a command which queries endpoint, such as
+// _design_docs, in the same way as _all_docs.
+func specialDocsCmd(endpoint string) *cobra.Command {
+	cmd := &cobra.Command{
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := kouch.GetContext(cmd)
+			o, err := getSpecialDocsOpts(ctx, cmd.Flags())
+			if err != nil {
+				return err
+			}
+			return getSpecialDocs(ctx, endpoint, o)
+		},
+	}
+	AddQueryFlags(cmd.Flags())
+	return cmd
+}
+
+func getSpecialDocsOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := getAllDocsOpts(ctx, flags)
+	if err != nil {
+		return nil, err
+	}
+	if o.Partition != "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s is not supported for design or local documents", kouch.FlagPartition)
+	}
+	return o, nil
+}
+
+func getSpecialDocs(ctx context.Context, endpoint string, o *kouch.Options) error {
+	if err := validateTarget(o.Target); err != nil {
+		return err
+	}
+	return util.ChttpDo(ctx, http.MethodGet, util.DatabasePath(o)+"/"+endpoint, o)
+}
@@ -0,0 +1,79 @@
+package alldocs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestGetSpecialDocsCmd(t *testing.T) {
+	type tst struct {
+		test.CmdTest
+		request  *string
+		expected string
+	}
+	tests := testy.NewTable()
+	server := func(t *testing.T) (*httptest.Server, *string) {
+		var request string
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request = r.Method + " " + r.URL.RequestURI()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"total_rows":1,"offset":0,"rows":[{"id":"_design/foo","key":"_design/foo","value":{"rev":"1-abc"}}]}`))
+		}))
+		tests.Cleanup(s.Close)
+		return s, &request
+	}
+	tests.Add("design-docs, no database", tst{
+		CmdTest: test.CmdTest{
+			Args:   []string{"design-docs", "--root", "http://localhost/"},
+			Err:    "No database name provided",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("design-docs, partition", tst{
+		CmdTest: test.CmdTest{
+			Args:   []string{"design-docs", "http://localhost/foo", "--partition", "bar"},
+			Err:    "--partition is not supported for design or local documents",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("design-docs", func(t *testing.T) interface{} {
+		s, request := server(t)
+		return tst{
+			CmdTest: test.CmdTest{
+				Args:   []string{"design-docs", s.URL + "/foo", "--limit", "10", "--startkey", `"_design/a"`},
+				Stdout: `{"offset":0,"rows":[{"id":"_design/foo","key":"_design/foo","value":{"rev":"1-abc"}}],"total_rows":1}`,
+			},
+			request:  request,
+			expected: "GET /foo/_design_docs?limit=10&startkey=%22_design%2Fa%22",
+		}
+	})
+	tests.Add("local-docs", func(t *testing.T) interface{} {
+		s, request := server(t)
+		return tst{
+			CmdTest: test.CmdTest{
+				Args:   []string{"local-docs", s.URL + "/foo", "--descending"},
+				Stdout: `{"offset":0,"rows":[{"id":"_design/foo","key":"_design/foo","value":{"rev":"1-abc"}}],"total_rows":1}`,
+			},
+			request:  request,
+			expected: "GET /foo/_local_docs?descending=true",
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt tst) {
+		test.ValidateCmdTest([]string{"get"})(t, tt.CmdTest)
+		if tt.request != nil {
+			if d := diff.Text(tt.expected, *tt.request); d != nil {
+				t.Errorf("Unexpected request:\n%s", d)
+			}
+		}
+	})
+}
@@ -2,6 +2,7 @@ package attachments
 
 import (
 	"context"
+	"fmt"
 	"mime"
 	"net/http"
 	"path/filepath"
@@ -35,6 +36,7 @@ func putAttCmd() *cobra.Command {
 	}
 	addCommonFlags(cmd.Flags())
 	cmd.Flags().BoolP(kouch.FlagAutoRev, kouch.FlagShortAutoRev, false, "Fetch the current rev before update. Use with caution!")
+	cmd.Flags().BoolP(kouch.FlagYes, kouch.FlagShortYes, false, "Do not prompt for confirmation when using a protected context.")
 
 	cmd.Flags().String(flagContentType, "", "Attachment MIME type.")
 	cmd.Flags().Bool(flagGuessContentType, false, "Attempt to guess the content type from the file. Falls back to 'application/octet-stream'.")
@@ -51,6 +53,9 @@ func putAttachmentCmd(cmd *cobra.Command, _ []string) error {
 	if err := validateTarget(o.Target); err != nil {
 		return err
 	}
+	if err := util.ConfirmMutation(o, cmd.Flags(), fmt.Sprintf("You are about to upload the attachment '%s' to the document '%s' in the database '%s'.", o.Filename, o.Document, o.Database), o.Database); err != nil {
+		return err
+	}
 	return util.ChttpDo(ctx, http.MethodPut, util.AttPath(o), o)
 }
 
@@ -0,0 +1,198 @@
+package bulk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	kio "github.com/go-kivik/kouch/io"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const flagDoc = "doc"
+
+func init() {
+	registry.Register([]string{"get"}, getBulkCmd)
+}
+
+func getBulkCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bulk [target]",
+		Short: "Fetches multiple documents.",
+		Long: "Fetches multiple documents, or document revisions, in a single request, using _bulk_get.\n\n" +
+			"Documents are specified with --" + flagDoc + ", or else read from the input, one per line. " +
+			"Each takes the format `id`, or `id rev`, or a JSON object with `id` and `rev` fields.\n\n" +
+			"The fetched documents are output one at a time. Documents which could not be fetched are reported on stderr.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: getBulkDocsCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}.")
+	f.StringArray(flagDoc, nil, "Document to fetch, in the format `id` or `id rev`. May be repeated.")
+	f.Bool(kouch.FlagRevs, false, "Include revision history of each document.")
+	f.Bool(kouch.FlagIncludeAttachments, false, "Include Base64-encoded content of attachments.")
+	f.Bool(kouch.FlagForceLatest, false, "Fetch the latest leaf revisions of the requested revisions.")
+	return cmd
+}
+
+type bulkGetDoc struct {
+	ID  string `json:"id"`
+	Rev string `json:"rev,omitempty"`
+}
+
+func getBulkDocsCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := getBulkOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if err := validateTarget(o.Target); err != nil {
+		return err
+	}
+	docs, err := bulkGetDocs(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	return getBulk(ctx, o, docs)
+}
+
+func getBulkOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	if e := o.SetParams(flags, kouch.FlagRevs, kouch.FlagIncludeAttachments, kouch.FlagForceLatest); e != nil {
+		return nil, e
+	}
+	return o, nil
+}
+
+// bulkGetDocs returns the documents requested with --doc, or else those read
+// from the input.
+func bulkGetDocs(ctx context.Context, flags *pflag.FlagSet) ([]bulkGetDoc, error) {
+	specs, err := flags.GetStringArray(flagDoc)
+	if err != nil {
+		return nil, err
+	}
+	if len(specs) == 0 {
+		if specs, err = readLines(kouch.Input(ctx)); err != nil {
+			return nil, err
+		}
+	}
+	docs := make([]bulkGetDoc, 0, len(specs))
+	for _, spec := range specs {
+		doc, err := parseDocSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No documents specified")
+	}
+	return docs, nil
+}
+
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, errors.WrapExitError(chttp.ExitReadError, s.Err())
+}
+
+var revRE = regexp.MustCompile(`^\d+-[0-9a-fA-F]+$`)
+
+// parseDocSpec parses a document specification, in the format `id`, `id rev`,
+// or `{"id":"...","rev":"..."}`.
+func parseDocSpec(spec string) (bulkGetDoc, error) {
+	var doc bulkGetDoc
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "{") {
+		if err := json.Unmarshal([]byte(spec), &doc); err != nil {
+			return doc, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid document specification '%s': %s", spec, err)
+		}
+	} else {
+		doc.ID = spec
+		// As document IDs may contain spaces, only treat the last field as a
+		// revision if it looks like one.
+		if i := strings.LastIndexAny(spec, " \t"); i > 0 && revRE.MatchString(spec[i+1:]) {
+			doc.ID, doc.Rev = strings.TrimSpace(spec[:i]), spec[i+1:]
+		}
+	}
+	if doc.ID == "" {
+		return doc, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid document specification '%s': no document ID", spec)
+	}
+	return doc, nil
+}
+
+type bulkGetResult struct {
+	ID   string `json:"id"`
+	Docs []struct {
+		OK    json.RawMessage `json:"ok"`
+		Error json.RawMessage `json:"error"`
+	} `json:"docs"`
+}
+
+func getBulk(ctx context.Context, o *kouch.Options, docs []bulkGetDoc) error {
+	w, err := kio.NewDocWriter(ctx)
+	if err != nil {
+		return err
+	}
+	c, err := o.NewClient()
+	if err != nil {
+		return err
+	}
+	o.Body = chttp.EncodeBody(map[string]interface{}{"docs": docs})
+	res, err := c.DoReq(ctx, http.MethodPost, util.DatabasePath(o)+"/_bulk_get", o.Options)
+	if err != nil {
+		return err
+	}
+	if err = chttp.ResponseError(res); err != nil {
+		return err
+	}
+	defer res.Body.Close() // nolint: errcheck
+	enc := json.NewEncoder(os.Stderr)
+	var count, failed int
+	_, err = util.StreamArray(res.Body, "results", func(raw json.RawMessage) error {
+		var result bulkGetResult
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return errors.WrapExitError(chttp.ExitWeirdReply, err)
+		}
+		for _, doc := range result.Docs {
+			count++
+			if len(doc.Error) > 0 {
+				failed++
+				if err := enc.Encode(doc.Error); err != nil {
+					return errors.WrapExitError(chttp.ExitWriteError, err)
+				}
+				continue
+			}
+			if err := w.WriteDoc(doc.OK); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return errors.NewExitError(chttp.ExitNotRetrieved, "%d of %d documents could not be retrieved", failed, count)
+	}
+	return nil
+}
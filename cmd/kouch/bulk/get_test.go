@@ -0,0 +1,141 @@
+package bulk
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+)
+
+func TestGetBulkOpts(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("defaults", test.OptionsTest{
+		Expected: &kouch.Options{
+			Target:  &kouch.Target{},
+			Options: &chttp.Options{},
+		},
+	})
+	tests.Add("all options", test.OptionsTest{
+		Args: []string{"foo", "--" + kouch.FlagRevs, "--" + kouch.FlagIncludeAttachments, "--" + kouch.FlagForceLatest},
+		Expected: &kouch.Options{
+			Target: &kouch.Target{Database: "foo"},
+			Options: &chttp.Options{
+				Query: url.Values{
+					"revs":        []string{"true"},
+					"attachments": []string{"true"},
+					"latest":      []string{"true"},
+				},
+			},
+		},
+	})
+
+	tests.Run(t, test.Options(getBulkCmd, getBulkOpts))
+}
+
+func TestParseDocSpec(t *testing.T) {
+	type pdsTest struct {
+		spec     string
+		expected bulkGetDoc
+		err      string
+	}
+	tests := testy.NewTable()
+	tests.Add("id only", pdsTest{
+		spec:     "foo",
+		expected: bulkGetDoc{ID: "foo"},
+	})
+	tests.Add("id and rev", pdsTest{
+		spec:     "foo 2-abc123",
+		expected: bulkGetDoc{ID: "foo", Rev: "2-abc123"},
+	})
+	tests.Add("id with space", pdsTest{
+		spec:     "foo bar",
+		expected: bulkGetDoc{ID: "foo bar"},
+	})
+	tests.Add("json", pdsTest{
+		spec:     `{"id":"foo","rev":"1-x"}`,
+		expected: bulkGetDoc{ID: "foo", Rev: "1-x"},
+	})
+	tests.Add("json without id", pdsTest{
+		spec: `{"rev":"1-x"}`,
+		err:  `Invalid document specification '{"rev":"1-x"}': no document ID`,
+	})
+
+	tests.Run(t, func(t *testing.T, test pdsTest) {
+		doc, err := parseDocSpec(test.spec)
+		testy.Error(t, test.err, err)
+		if d := diff.Interface(test.expected, doc); d != nil {
+			t.Error(d)
+		}
+	})
+}
+
+func TestGetBulkCmd(t *testing.T) {
+	type gbTest struct {
+		test.CmdTest
+		requests func() []string
+		expected []string
+	}
+	tests := testy.NewTable()
+	tests.Add("validation fails", gbTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"--" + flagDoc, "foo"},
+			Err:    "No database name provided",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("no documents", gbTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost/foo", "-d", "\n"},
+			Err:    "No documents specified",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("flags", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Body: `{"results":[
+			{"id":"a","docs":[{"ok":{"_id":"a","_rev":"1-x"}}]},
+			{"id":"b","docs":[{"ok":{"_id":"b","_rev":"2-y"}},{"ok":{"_id":"b","_rev":"2-z"}}]}
+		]}`})
+		tests.Cleanup(s.Close)
+		return gbTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "--" + flagDoc, "a", "--" + flagDoc, "b 1-abc", "--" + kouch.FlagForceLatest},
+				Stdout: `{"_id":"a","_rev":"1-x"}` + "\n" + `{"_id":"b","_rev":"2-y"}` + "\n" + `{"_id":"b","_rev":"2-z"}` + "\n",
+			},
+			requests: s.Requests,
+			expected: []string{`POST /foo/_bulk_get?latest=true {"docs":[{"id":"a"},{"id":"b","rev":"1-abc"}]}` + "\n"},
+		}
+	})
+	tests.Add("input lines, with error", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Body: `{"results":[
+			{"id":"a","docs":[{"ok":{"_id":"a","_rev":"1-x"}}]},
+			{"id":"c","docs":[{"error":{"id":"c","rev":"undefined","error":"not_found","reason":"missing"}}]}
+		]}`})
+		tests.Cleanup(s.Close)
+		return gbTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "-d", "a 1-x\n\nc\n"},
+				Stdout: `{"_id":"a","_rev":"1-x"}` + "\n",
+				Stderr: `{"id":"c","rev":"undefined","error":"not_found","reason":"missing"}` + "\n",
+				Err:    "1 of 2 documents could not be retrieved",
+				Status: chttp.ExitNotRetrieved,
+			},
+			requests: s.Requests,
+			expected: []string{`POST /foo/_bulk_get {"docs":[{"id":"a","rev":"1-x"},{"id":"c"}]}` + "\n"},
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt gbTest) {
+		test.ValidateCmdTest([]string{"get", "bulk"})(t, tt.CmdTest)
+		if tt.requests != nil {
+			if d := diff.Interface(tt.expected, tt.requests()); d != nil {
+				t.Errorf("Unexpected requests:\n%s", d)
+			}
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	kio "github.com/go-kivik/kouch/io"
+	"github.com/go-kivik/kouch/kouchio"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const flagBatchSize = "batch-size"
+
+const defaultBatchSize = 1000
+
+func init() {
+	registry.Register([]string{"put"}, putBulkCmd)
+}
+
+func putBulkCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bulk [target]",
+		Short: "Creates or updates multiple documents.",
+		Long: "Creates or updates multiple documents, using _bulk_docs.\n\n" +
+			"Input may be a JSON array of documents, JSON Lines, or (with --" + kouch.FlagInputFormat + "=yaml, or a .yaml file given with --" + kouch.FlagData + ") YAML documents. " +
+			"Large inputs are split into multiple requests, according to --" + flagBatchSize + ".\n\n" +
+			"A summary table of the results is printed, unless --" + kouch.FlagOutputFormat + " is given, in which case the results are output in that format. " +
+			"If any document fails, kouch exits with a non-zero status.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: putBulkDocsCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}.")
+	f.String(kouch.FlagInputFormat, kio.InputFormatJSON, "Input format. Supported options: `json`, `yaml`.")
+	f.Int(flagBatchSize, defaultBatchSize, "Maximum number of documents to send per request.")
+	f.Bool(kouch.FlagNewEdits, true, "When disabled, document revisions are stored as provided.")
+	f.Bool(kouch.FlagAllOrNothing, false, "Commit all documents, or none, in each request. Only supported by CouchDB 1.x.")
+	f.BoolP(kouch.FlagYes, kouch.FlagShortYes, false, "Do not prompt for confirmation when using a protected context.")
+	return cmd
+}
+
+type bulkPut struct {
+	*util.BulkUploader
+	o      *kouch.Options
+	format string
+	table  bool
+}
+
+func putBulkDocsCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	b, err := putBulkOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if err := validateTarget(b.o.Target); err != nil {
+		return err
+	}
+	if err := util.ConfirmMutation(b.o, cmd.Flags(), fmt.Sprintf("You are about to update documents in the database '%s'.", b.o.Database), b.o.Database); err != nil {
+		return err
+	}
+	return b.put(ctx, kouch.Input(ctx), kouch.Output(ctx))
+}
+
+func putBulkOpts(ctx context.Context, flags *pflag.FlagSet) (*bulkPut, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	b := &bulkPut{
+		o:            o,
+		BulkUploader: &util.BulkUploader{Path: util.DatabasePath(o) + "/_bulk_docs", Workers: 1},
+		// Print a table, unless a specific output format was requested.
+		table: !flags.Changed(kouch.FlagOutputFormat),
+	}
+	if b.format, err = kio.DocInputFormat(flags); err != nil {
+		return nil, err
+	}
+	multi, err := kio.MultipleDataDocs(flags)
+	if err != nil {
+		return nil, err
+	}
+	if multi != "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s accepts a single document; to provide multiple documents, use --%s with --%s", multi, kouch.FlagData, kouch.FlagInputFormat)
+	}
+	if b.BatchSize, err = flags.GetInt(flagBatchSize); err != nil {
+		return nil, err
+	}
+	if b.BatchSize < 1 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must be a positive integer", flagBatchSize)
+	}
+	newEdits, err := flags.GetBool(kouch.FlagNewEdits)
+	if err != nil {
+		return nil, err
+	}
+	b.PreserveRevisions = !newEdits
+	if b.AllOrNothing, err = flags.GetBool(kouch.FlagAllOrNothing); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *bulkPut) put(ctx context.Context, in io.Reader, out io.Writer) error {
+	docs, err := kio.NewDocReader(in, b.format)
+	if err != nil {
+		return err
+	}
+	if b.Client, err = b.o.NewClient(); err != nil {
+		return err
+	}
+	results := []util.BulkResult{}
+	var failed int
+	b.OnResult = func(_ json.RawMessage, result util.BulkResult) error {
+		if result.Error != "" {
+			failed++
+		}
+		results = append(results, result)
+		return nil
+	}
+	count, err := b.Upload(ctx, docs)
+	if err != nil {
+		return err
+	}
+	if b.table {
+		err = writeTable(kouchio.Underlying(out), results)
+	} else {
+		err = json.NewEncoder(out).Encode(results)
+	}
+	if err != nil {
+		return err
+	}
+	if e := kouchio.CloseWriter(out); e != nil {
+		return e
+	}
+	if failed > 0 {
+		return errors.NewExitError(chttp.ExitPostError, "%d of %d documents failed", failed, count)
+	}
+	return nil
+}
+
+func writeTable(w io.Writer, results []util.BulkResult) error {
+	rows := make([][]string, len(results))
+	for i, r := range results {
+		rows[i] = []string{r.ID, r.Rev, r.Error, r.Reason}
+	}
+	return util.WriteTable(w, []string{"ID", "REV", "ERROR", "REASON"}, rows)
+}
+
+func validateTarget(t *kouch.Target) error {
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package bulk
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/put"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestPutBulkCmd(t *testing.T) {
+	type pbTest struct {
+		test.CmdTest
+		requests func() []string
+		expected []string
+	}
+	tests := testy.NewTable()
+	tests.Add("validation fails", pbTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"-d", "[]"},
+			Err:    "No database name provided",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("invalid batch size", pbTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost/foo", "--" + flagBatchSize, "0", "-d", "[]"},
+			Err:    "--batch-size must be a positive integer",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("chunked, with failure", func(t *testing.T) interface{} {
+		s := test.NewServer(t,
+			test.Response{Status: http.StatusCreated, Body: `[{"ok":true,"id":"a","rev":"1-x"},{"id":"b","error":"conflict","reason":"Document update conflict."}]`},
+			test.Response{Status: http.StatusCreated, Body: `[{"ok":true,"id":"c","rev":"1-z"}]`},
+		)
+		tests.Cleanup(s.Close)
+		return pbTest{
+			CmdTest: test.CmdTest{
+				Args: []string{s.URL + "/foo", "--" + flagBatchSize, "2", "-d", `[{"_id":"a"},{"_id":"b"},{"_id":"c"}]`},
+				Stdout: `ID  REV  ERROR     REASON
+a   1-x
+b        conflict  Document update conflict.
+c   1-z
+`,
+				Err:    "1 of 3 documents failed",
+				Status: chttp.ExitPostError,
+			},
+			requests: s.Requests,
+			expected: []string{
+				`POST /foo/_bulk_docs {"docs":[{"_id":"a"},{"_id":"b"}]}` + "\n",
+				`POST /foo/_bulk_docs {"docs":[{"_id":"c"}]}` + "\n",
+			},
+		}
+	})
+	tests.Add("yaml input, json output", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Status: http.StatusCreated, Body: `[]`})
+		tests.Cleanup(s.Close)
+		return pbTest{
+			CmdTest: test.CmdTest{
+				Args: []string{s.URL + "/foo", "--" + kouch.FlagInputFormat, "yaml", "--" + kouch.FlagNewEdits + "=false",
+					"--" + kouch.FlagAllOrNothing, "-F", "json", "-d", "_id: a\n_rev: 1-x\n---\n_id: b\n_rev: 1-y\n"},
+				Stdout: "[]\n",
+			},
+			requests: s.Requests,
+			expected: []string{
+				`POST /foo/_bulk_docs {"docs":[{"_id":"a","_rev":"1-x"},{"_id":"b","_rev":"1-y"}],"new_edits":false,"all_or_nothing":true}` + "\n",
+			},
+		}
+	})
+
+	tests.Add("yaml file", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Status: http.StatusCreated, Body: `[]`})
+		tests.Cleanup(s.Close)
+		f, err := ioutil.TempFile("", "kouch-bulk-*.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests.Cleanup(func() { _ = os.Remove(f.Name()) })
+		if _, err := f.WriteString("_id: a\n---\n_id: b\n"); err != nil {
+			t.Fatal(err)
+		}
+		_ = f.Close()
+		return pbTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "-F", "json", "-d", "@" + f.Name()},
+				Stdout: "[]\n",
+			},
+			requests: s.Requests,
+			expected: []string{
+				`POST /foo/_bulk_docs {"docs":[{"_id":"a"},{"_id":"b"}]}` + "\n",
+			},
+		}
+	})
+	tests.Add("multiple yaml documents in --data-yaml", pbTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost/foo", "--" + kouch.FlagDataYAML, "_id: a\n---\n_id: b\n"},
+			Err:    "--data-yaml accepts a single document; to provide multiple documents, use --data with --input-format",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+
+	tests.Run(t, func(t *testing.T, tt pbTest) {
+		test.ValidateCmdTest([]string{"put", "bulk"})(t, tt.CmdTest)
+		if tt.requests != nil {
+			if d := diff.Interface(tt.expected, tt.requests()); d != nil {
+				t.Errorf("Unexpected requests:\n%s", d)
+			}
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package changes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	kio "github.com/go-kivik/kouch/io"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Feed types
+const (
+	feedNormal      = "normal"
+	feedLongpoll    = "longpoll"
+	feedContinuous  = "continuous"
+	feedEventSource = "eventsource"
+)
+
+// Built-in filters
+const (
+	filterDocIDs   = "_doc_ids"
+	filterSelector = "_selector"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getChangesCmd)
+}
+
+func getChangesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "changes [target]",
+		Short: "Fetches the changes feed of a database.",
+		Long: "Fetches the changes feed of a database.\n\n" +
+			"Each change is written as a separate document, as soon as it is received, so that the output may be piped into other tools. " +
+			"For normal and longpoll feeds, a final document containing `last_seq` and `pending` follows the changes.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: getChangesFeedCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}.")
+	f.String(kouch.FlagFeed, feedNormal, "The type of feed. Supported options: `normal`, `longpoll`, `continuous`, `eventsource`.")
+	f.String(kouch.FlagSince, "", "Start the results from the change immediately after the given update sequence. Use `now` to receive only new changes.")
+	f.Int(kouch.FlagLimit, 0, "The maximum number of changes to be returned.")
+	f.String(kouch.FlagFilter, "", "The filter function, in the format `ddoc/name`, to apply to the feed.")
+	f.StringSlice(kouch.FlagDocIDs, nil, "Return only changes to the specified document IDs.")
+	f.String(kouch.FlagSelector, "", "Return only changes to documents matching the selector, in YAML or JSON format. Prefix with '@' to specify a filename.")
+	util.AddWhereFlags(f)
+	f.Bool(kouch.FlagIncludeDocs, false, "Include the associated document with each change.")
+	f.Int(kouch.FlagHeartbeat, 0, "Period, in milliseconds, after which an empty line is sent, to keep the connection alive. Applies to longpoll, continuous and eventsource feeds.")
+	f.String(kouch.FlagStyle, "", "Specifies how many revisions are returned per change. Supported options: `main_only`, `all_docs`.")
+	return cmd
+}
+
+func getChangesFeedCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := getChangesOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	body, err := changesBody(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if done, err := util.ExplainSelector(ctx, cmd.Flags(), body["selector"]); done || err != nil {
+		return err
+	}
+	return getChanges(ctx, o, body)
+}
+
+func getChangesOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	if e := o.SetParams(flags,
+		kouch.FlagFeed, kouch.FlagSince, kouch.FlagLimit, kouch.FlagFilter,
+		kouch.FlagIncludeDocs, kouch.FlagHeartbeat, kouch.FlagStyle,
+	); e != nil {
+		return nil, e
+	}
+	filter, err := builtinFilter(flags)
+	if err != nil {
+		return nil, err
+	}
+	if filter != "" {
+		if current := o.Query().Get("filter"); current != "" && current != filter {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s may not be used with --%s or --%s", kouch.FlagFilter, kouch.FlagDocIDs, kouch.FlagSelector)
+		}
+		o.Query().Set("filter", filter)
+	}
+	return o, nil
+}
+
+// builtinFilter returns the built-in filter implied by --doc-ids, or
+// --selector or --where, if any is set.
+func builtinFilter(flags *pflag.FlagSet) (string, error) {
+	docIDs, err := flags.GetStringSlice(kouch.FlagDocIDs)
+	if err != nil {
+		return "", err
+	}
+	selector, err := flags.GetString(kouch.FlagSelector)
+	if err != nil {
+		return "", err
+	}
+	where, err := flags.GetStringArray(kouch.FlagWhere)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case len(docIDs) > 0 && selector != "":
+		return "", errors.NewExitError(chttp.ExitFailedToInitialize, "Must not use --%s and --%s together", kouch.FlagDocIDs, kouch.FlagSelector)
+	case len(docIDs) > 0 && len(where) > 0:
+		return "", errors.NewExitError(chttp.ExitFailedToInitialize, "Must not use --%s and --%s together", kouch.FlagDocIDs, kouch.FlagWhere)
+	case len(docIDs) > 0:
+		return filterDocIDs, nil
+	case selector != "" || len(where) > 0:
+		return filterSelector, nil
+	}
+	return "", nil
+}
+
+// changesBody returns the request body required by --doc-ids, or --selector
+// and --where, or nil if none is set.
+func changesBody(flags *pflag.FlagSet) (map[string]interface{}, error) {
+	docIDs, err := flags.GetStringSlice(kouch.FlagDocIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(docIDs) > 0 {
+		return map[string]interface{}{"doc_ids": docIDs}, nil
+	}
+	selector, err := flags.GetString(kouch.FlagSelector)
+	if err != nil {
+		return nil, err
+	}
+	where, err := util.WhereSelector(flags)
+	if err != nil {
+		return nil, err
+	}
+	if selector == "" && where == nil {
+		return nil, nil
+	}
+	var s interface{}
+	if selector != "" {
+		if s, err = kio.ParseData(kouch.FlagSelector, selector); err != nil {
+			return nil, err
+		}
+	}
+	if where != nil {
+		s = util.AndSelectors(s, where)
+	}
+	return map[string]interface{}{"selector": s}, nil
+}
+
+func getChanges(ctx context.Context, o *kouch.Options, body map[string]interface{}) error {
+	if err := validateTarget(o.Target); err != nil {
+		return err
+	}
+	w, err := kio.NewDocWriter(ctx)
+	if err != nil {
+		return err
+	}
+	c, err := o.NewClient()
+	if err != nil {
+		return err
+	}
+	method := http.MethodGet
+	if body != nil {
+		method = http.MethodPost
+		o.Body = chttp.EncodeBody(body)
+	}
+	res, err := c.DoReq(ctx, method, util.DatabasePath(o)+"/_changes", o.Options)
+	if err != nil {
+		return err
+	}
+	if err = chttp.ResponseError(res); err != nil {
+		return err
+	}
+	defer res.Body.Close() // nolint: errcheck
+	switch o.Query().Get("feed") {
+	case feedContinuous:
+		return util.StreamLines(res.Body, w.WriteDoc)
+	case feedEventSource:
+		return util.StreamLines(res.Body, func(line []byte) error {
+			if !bytes.HasPrefix(line, []byte("data:")) {
+				return nil
+			}
+			data := bytes.TrimSpace(line[len("data:"):])
+			if len(data) == 0 {
+				return nil
+			}
+			return w.WriteDoc(data)
+		})
+	}
+	trailer, err := util.StreamArray(res.Body, "results", func(change json.RawMessage) error {
+		return w.WriteDoc(change)
+	})
+	if err != nil {
+		return err
+	}
+	tr, err := json.Marshal(trailer)
+	if err != nil {
+		return err
+	}
+	return w.WriteDoc(tr)
+}
+
+func validateTarget(t *kouch.Target) error {
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package changes
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestGetChangesOpts(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("defaults", test.OptionsTest{
+		Expected: &kouch.Options{
+			Target:  &kouch.Target{},
+			Options: &chttp.Options{},
+		},
+	})
+	tests.Add("feed options", test.OptionsTest{
+		Args: []string{"foo", "--" + kouch.FlagFeed, "continuous", "--" + kouch.FlagSince, "now",
+			"--" + kouch.FlagHeartbeat, "1000", "--" + kouch.FlagIncludeDocs, "--" + kouch.FlagStyle, "all_docs",
+			"--" + kouch.FlagLimit, "10", "--" + kouch.FlagFilter, "app/important"},
+		Expected: &kouch.Options{
+			Target: &kouch.Target{Database: "foo"},
+			Options: &chttp.Options{
+				Query: url.Values{
+					"feed":         []string{"continuous"},
+					"since":        []string{"now"},
+					"heartbeat":    []string{"1000"},
+					"include_docs": []string{"true"},
+					"style":        []string{"all_docs"},
+					"limit":        []string{"10"},
+					"filter":       []string{"app/important"},
+				},
+			},
+		},
+	})
+	tests.Add("invalid feed", test.OptionsTest{
+		Args:   []string{"--" + kouch.FlagFeed, "oink"},
+		Err:    "Invalid value for --feed. Supported options: `normal`, `longpoll`, `continuous`, `eventsource`",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid style", test.OptionsTest{
+		Args:   []string{"--" + kouch.FlagStyle, "oink"},
+		Err:    "Invalid value for --style. Supported options: `main_only`, `all_docs`",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid heartbeat", test.OptionsTest{
+		Args:   []string{"--" + kouch.FlagHeartbeat, "-1"},
+		Err:    "Invalid value for --heartbeat. Must be a positive integer",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("doc ids", test.OptionsTest{
+		Args: []string{"--" + kouch.FlagDocIDs, "a,b"},
+		Expected: &kouch.Options{
+			Target: &kouch.Target{},
+			Options: &chttp.Options{
+				Query: url.Values{"filter": []string{"_doc_ids"}},
+			},
+		},
+	})
+	tests.Add("selector", test.OptionsTest{
+		Args: []string{"--" + kouch.FlagSelector, "type: user"},
+		Expected: &kouch.Options{
+			Target: &kouch.Target{},
+			Options: &chttp.Options{
+				Query: url.Values{"filter": []string{"_selector"}},
+			},
+		},
+	})
+	tests.Add("doc ids and selector", test.OptionsTest{
+		Args:   []string{"--" + kouch.FlagDocIDs, "a", "--" + kouch.FlagSelector, "type: user"},
+		Err:    "Must not use --doc-ids and --selector together",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("where", test.OptionsTest{
+		Args: []string{"--" + kouch.FlagWhere, "type=user"},
+		Expected: &kouch.Options{
+			Target: &kouch.Target{},
+			Options: &chttp.Options{
+				Query: url.Values{"filter": []string{"_selector"}},
+			},
+		},
+	})
+	tests.Add("doc ids and where", test.OptionsTest{
+		Args:   []string{"--" + kouch.FlagDocIDs, "a", "--" + kouch.FlagWhere, "type=user"},
+		Err:    "Must not use --doc-ids and --where together",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("filter and selector", test.OptionsTest{
+		Args:   []string{"--" + kouch.FlagFilter, "app/important", "--" + kouch.FlagSelector, "type: user"},
+		Err:    "--filter may not be used with --doc-ids or --selector",
+		Status: chttp.ExitFailedToInitialize,
+	})
+
+	tests.Run(t, test.Options(getChangesCmd, getChangesOpts))
+}
+
+func TestGetChangesCmd(t *testing.T) {
+	serve := func(t *testing.T, method, path, reqBody, resBody string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != method || r.URL.RequestURI() != path {
+				t.Errorf("Unexpected request: %s %s", r.Method, r.URL.RequestURI())
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			if string(body) != reqBody {
+				t.Errorf("Unexpected request body: %s", string(body))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(resBody))
+		}))
+	}
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("normal feed", func(t *testing.T) interface{} {
+		s := serve(t, "GET", "/foo/_changes", "", `{"results":[
+			{"seq":"1-a","id":"a","changes":[{"rev":"1-x"}]},
+			{"seq":"2-a","id":"b","changes":[{"rev":"1-y"}]}
+		],"last_seq":"2-a","pending":0}`)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo"},
+			Stdout: `{"changes":[{"rev":"1-x"}],"id":"a","seq":"1-a"}
+{"changes":[{"rev":"1-y"}],"id":"b","seq":"2-a"}
+{"last_seq":"2-a","pending":0}
+`,
+		}
+	})
+	tests.Add("continuous feed, yaml", func(t *testing.T) interface{} {
+		s := serve(t, "GET", "/foo/_changes?feed=continuous&heartbeat=500", "", `{"seq":"1-a","id":"a","changes":[{"rev":"1-x"}]}
+
+{"seq":"2-a","id":"b","changes":[{"rev":"1-y"}]}
+`)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo", "--" + kouch.FlagFeed, "continuous", "--" + kouch.FlagHeartbeat, "500", "-F", "yaml"},
+			Stdout: `changes:
+- rev: 1-x
+id: a
+seq: 1-a
+---
+changes:
+- rev: 1-y
+id: b
+seq: 2-a
+`,
+		}
+	})
+	tests.Add("eventsource feed", func(t *testing.T) interface{} {
+		s := serve(t, "GET", "/foo/_changes?feed=eventsource", "", `data: {"seq":"1-a","id":"a","changes":[{"rev":"1-x"}]}
+id: 1-a
+
+event: heartbeat
+data:
+
+`)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + kouch.FlagFeed, "eventsource"},
+			Stdout: `{"changes":[{"rev":"1-x"}],"id":"a","seq":"1-a"}` + "\n",
+		}
+	})
+	tests.Add("doc ids", func(t *testing.T) interface{} {
+		s := serve(t, "POST", "/foo/_changes?filter=_doc_ids", `{"doc_ids":["a","b"]}`+"\n", `{"results":[],"last_seq":"0","pending":0}`)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + kouch.FlagDocIDs, "a,b"},
+			Stdout: `{"last_seq":"0","pending":0}` + "\n",
+		}
+	})
+	tests.Add("selector", func(t *testing.T) interface{} {
+		s := serve(t, "POST", "/foo/_changes?filter=_selector", `{"selector":{"age":{"$gt":30}}}`+"\n", `{"results":[],"last_seq":"0","pending":0}`)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + kouch.FlagSelector, `{"age": {"$gt": 30}}`},
+			Stdout: `{"last_seq":"0","pending":0}` + "\n",
+		}
+	})
+	tests.Add("selector and where", func(t *testing.T) interface{} {
+		s := serve(t, "POST", "/foo/_changes?filter=_selector", `{"selector":{"$and":[{"age":{"$gt":30}},{"type":{"$eq":"user"}}]}}`+"\n", `{"results":[],"last_seq":"0","pending":0}`)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + kouch.FlagSelector, `{"age": {"$gt": 30}}`, "--" + kouch.FlagWhere, "type=user"},
+			Stdout: `{"last_seq":"0","pending":0}` + "\n",
+		}
+	})
+	tests.Add("explain selector", test.CmdTest{
+		Args:   []string{"--" + kouch.FlagWhere, "age>=30", "--" + kouch.FlagWhere, "email exists", "--" + kouch.FlagExplainSelector},
+		Stdout: `{"age":{"$gte":30},"email":{"$exists":true}}`,
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "changes"}))
+}
@@ -0,0 +1,17 @@
+package copy
+
+import (
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registry.Register(nil, copyCmd)
+}
+
+func copyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "copy",
+		Short: "Copy a resource.",
+	}
+}
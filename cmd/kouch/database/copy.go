@@ -0,0 +1,378 @@
+package database
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/go-kivik/kouch/kouchio"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagBatchSize = "batch-size"
+	flagRestart   = "restart"
+)
+
+const defaultCopyBatchSize = 100
+
+func init() {
+	registry.Register([]string{"copy"}, copyDbCmd)
+}
+
+func copyDbCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "database <source> <destination>",
+		Aliases: []string{"db"},
+		Short:   "Copies a database.",
+		Long: "Copies a database, including all document revisions and attachments, by reading from the source and writing to the destination.\n\n" +
+			"Unlike replication, the source and destination servers need not be able to reach one another; only kouch must be able to reach both.\n\n" +
+			"The source and destination may each be a full URL, such as http://localhost:5984/db, or a named context and database, such as prod/db. " +
+			"A database name alone refers to the default context.\n\n" +
+			"Progress is checkpointed in a _local document in the destination database, so an interrupted copy resumes where it left off.",
+		Args:              cobra.ExactArgs(2),
+		PersistentPreRunE: util.PositionalArgs,
+		RunE:              copyDatabaseCmd,
+	}
+	f := cmd.Flags()
+	f.Int(flagBatchSize, defaultCopyBatchSize, "Number of changes to process per batch.")
+	f.Bool(flagRestart, false, "Ignore any existing checkpoint, and copy from the beginning.")
+	f.BoolP(kouch.FlagYes, kouch.FlagShortYes, false, "Do not prompt for confirmation when the destination context is protected.")
+	return cmd
+}
+
+type copier struct {
+	src, dst     *kouch.Options
+	srcC, dstC   *chttp.Client
+	batchSize    int
+	restart      bool
+	checkpointID string
+	rejects      io.Writer
+}
+
+func copyDatabaseCmd(cmd *cobra.Command, args []string) error {
+	ctx := kouch.GetContext(cmd)
+	c, err := copyDatabaseOpts(ctx, cmd.Flags(), args)
+	if err != nil {
+		return err
+	}
+	prompt := fmt.Sprintf("You are about to copy documents to the database '%s'.", c.dst.Database)
+	if err := util.ConfirmMutation(c.dst, cmd.Flags(), prompt, c.dst.Database); err != nil {
+		return err
+	}
+	return c.copy(ctx, kouch.Output(ctx))
+}
+
+func copyDatabaseOpts(ctx context.Context, flags *pflag.FlagSet, args []string) (*copier, error) {
+	c := &copier{
+		src:     kouch.NewOptions(),
+		dst:     kouch.NewOptions(),
+		rejects: os.Stderr,
+	}
+	var err error
+	if c.src.Target, err = copyTarget(ctx, args[0]); err != nil {
+		return nil, err
+	}
+	if c.dst.Target, err = copyTarget(ctx, args[1]); err != nil {
+		return nil, err
+	}
+	if c.batchSize, err = flags.GetInt(flagBatchSize); err != nil {
+		return nil, err
+	}
+	if c.batchSize < 1 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must be a positive integer", flagBatchSize)
+	}
+	if c.restart, err = flags.GetBool(flagRestart); err != nil {
+		return nil, err
+	}
+	if c.srcC, err = c.src.NewClient(); err != nil {
+		return nil, err
+	}
+	if c.dstC, err = c.dst.NewClient(); err != nil {
+		return nil, err
+	}
+	c.checkpointID = checkpointID(c.src.Target, c.dst.Target)
+	return c, nil
+}
+
+// copyTarget parses src as a full URL, a named context followed by a database
+// name, or a database name relative to the default context.
+func copyTarget(ctx context.Context, src string) (*kouch.Target, error) {
+	conf := kouch.Conf(ctx)
+	var defCtx *kouch.Context
+	if parts := strings.SplitN(src, "/", 2); len(parts) == 2 {
+		if named, ok := conf.Ctx(parts[0]); ok {
+			defCtx = named
+			src = parts[1]
+		}
+	}
+	t, err := kouch.ParseTarget(kouch.TargetDatabase, src)
+	if err != nil {
+		return nil, err
+	}
+	if defCtx == nil && t.Root == "" {
+		defCtx, _ = conf.DefaultCtx()
+	}
+	if defCtx != nil {
+		t.Root = defCtx.Root
+		t.User = defCtx.User
+		t.Password = defCtx.Password
+		t.Protected = defCtx.Protected
+	}
+	if t.Root == "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided for '%s'", src)
+	}
+	return t, nil
+}
+
+// checkpointID returns the ID of the _local document used to record the
+// progress of copying between src and dst.
+func checkpointID(src, dst *kouch.Target) string {
+	sum := md5.Sum([]byte(src.Root + "/" + src.Database + "\n" + dst.Root + "/" + dst.Database))
+	return "_local/kouch-copy-" + hex.EncodeToString(sum[:])
+}
+
+type checkpoint struct {
+	ID      string          `json:"_id"`
+	Rev     string          `json:"_rev,omitempty"`
+	Source  string          `json:"source"`
+	Target  string          `json:"target"`
+	LastSeq json.RawMessage `json:"last_seq"`
+}
+
+type change struct {
+	Seq     json.RawMessage `json:"seq"`
+	ID      string          `json:"id"`
+	Changes []struct {
+		Rev string `json:"rev"`
+	} `json:"changes"`
+}
+
+type changesPage struct {
+	Results []change        `json:"results"`
+	LastSeq json.RawMessage `json:"last_seq"`
+}
+
+type copySummary struct {
+	Changes   int             `json:"changes"`
+	Revisions int             `json:"revisions"`
+	Failed    int             `json:"failed"`
+	LastSeq   json.RawMessage `json:"last_seq"`
+}
+
+type copyReject struct {
+	util.BulkResult
+	Doc json.RawMessage `json:"doc,omitempty"`
+}
+
+func (c *copier) copy(ctx context.Context, out io.Writer) error {
+	cp, err := c.readCheckpoint(ctx)
+	if err != nil {
+		return err
+	}
+	summary := copySummary{LastSeq: cp.LastSeq}
+	for {
+		page, err := c.changes(ctx, cp.LastSeq)
+		if err != nil {
+			return err
+		}
+		if len(page.Results) == 0 {
+			break
+		}
+		summary.Changes += len(page.Results)
+		docs, err := c.missing(ctx, page.Results)
+		if err != nil {
+			return err
+		}
+		summary.Revisions += len(docs)
+		failed, err := c.write(ctx, docs)
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			// Don't checkpoint past failed documents, so they are retried
+			// on the next run.
+			summary.Failed = failed
+			break
+		}
+		cp.LastSeq = page.LastSeq
+		if err := c.writeCheckpoint(ctx, cp); err != nil {
+			return err
+		}
+		summary.LastSeq = cp.LastSeq
+		if len(page.Results) < c.batchSize {
+			break
+		}
+	}
+	if err := json.NewEncoder(out).Encode(summary); err != nil {
+		return err
+	}
+	if err := kouchio.CloseWriter(out); err != nil {
+		return err
+	}
+	if summary.Failed > 0 {
+		return errors.NewExitError(chttp.ExitPostError, "%d of %d document revisions failed to copy", summary.Failed, summary.Revisions)
+	}
+	return nil
+}
+
+func (c *copier) checkpointPath() string {
+	return util.DatabasePath(c.dst) + "/" + c.checkpointID
+}
+
+func (c *copier) readCheckpoint(ctx context.Context) (*checkpoint, error) {
+	cp := &checkpoint{}
+	_, err := c.dstC.DoJSON(ctx, http.MethodGet, c.checkpointPath(), nil, cp)
+	if err != nil && kivik.StatusCode(err) != http.StatusNotFound {
+		return nil, err
+	}
+	cp.ID = c.checkpointID
+	cp.Source = c.src.Root + "/" + c.src.Database
+	cp.Target = c.dst.Root + "/" + c.dst.Database
+	if c.restart || len(cp.LastSeq) == 0 {
+		cp.LastSeq = json.RawMessage("0")
+	}
+	return cp, nil
+}
+
+func (c *copier) writeCheckpoint(ctx context.Context, cp *checkpoint) error {
+	var result struct {
+		Rev string `json:"rev"`
+	}
+	_, err := c.dstC.DoJSON(ctx, http.MethodPut, c.checkpointPath(), &chttp.Options{
+		Body: chttp.EncodeBody(cp),
+	}, &result)
+	if err != nil {
+		return err
+	}
+	cp.Rev = result.Rev
+	return nil
+}
+
+// seqParam converts a sequence, which may be a JSON string (CouchDB 2.x+) or
+// number (CouchDB 1.x), to a query parameter value.
+func seqParam(seq json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(seq, &s); err == nil {
+		return s
+	}
+	return string(seq)
+}
+
+func (c *copier) changes(ctx context.Context, since json.RawMessage) (*changesPage, error) {
+	query := url.Values{
+		"style": []string{"all_docs"},
+		"since": []string{seqParam(since)},
+		"limit": []string{strconv.Itoa(c.batchSize)},
+	}
+	page := &changesPage{}
+	_, err := c.srcC.DoJSON(ctx, http.MethodGet, util.DatabasePath(c.src)+"/_changes", &chttp.Options{
+		Query: query,
+	}, page)
+	return page, err
+}
+
+// missing returns the revisions listed in changes which are missing from the
+// destination, along with their revision histories and attachments.
+func (c *copier) missing(ctx context.Context, changes []change) ([]json.RawMessage, error) {
+	revs := make(map[string][]string, len(changes))
+	for _, ch := range changes {
+		for _, rev := range ch.Changes {
+			revs[ch.ID] = append(revs[ch.ID], rev.Rev)
+		}
+	}
+	diff := map[string]struct {
+		Missing []string `json:"missing"`
+	}{}
+	_, err := c.dstC.DoJSON(ctx, http.MethodPost, util.DatabasePath(c.dst)+"/_revs_diff", &chttp.Options{
+		Body: chttp.EncodeBody(revs),
+	}, &diff)
+	if err != nil {
+		return nil, err
+	}
+	var docs []json.RawMessage
+	for _, ch := range changes {
+		missing := diff[ch.ID].Missing
+		if len(missing) == 0 {
+			continue
+		}
+		revDocs, err := c.openRevs(ctx, ch.ID, missing)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, revDocs...)
+	}
+	return docs, nil
+}
+
+func (c *copier) openRevs(ctx context.Context, docID string, revs []string) ([]json.RawMessage, error) {
+	openRevs, err := json.Marshal(revs)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{
+		"open_revs":   []string{string(openRevs)},
+		"revs":        []string{"true"},
+		"attachments": []string{"true"},
+	}
+	var results []struct {
+		OK json.RawMessage `json:"ok"`
+	}
+	path := util.DatabasePath(c.src) + "/" + chttp.EncodeDocID(docID)
+	_, err = c.srcC.DoJSON(ctx, http.MethodGet, path, &chttp.Options{
+		Accept: "application/json",
+		Query:  query,
+	}, &results)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]json.RawMessage, 0, len(results))
+	for _, result := range results {
+		if len(result.OK) > 0 {
+			docs = append(docs, result.OK)
+		}
+	}
+	return docs, nil
+}
+
+// write writes docs to the destination, returning the number of documents
+// which failed.
+func (c *copier) write(ctx context.Context, docs []json.RawMessage) (int, error) {
+	if len(docs) == 0 {
+		return 0, nil
+	}
+	enc := json.NewEncoder(c.rejects)
+	var failed int
+	src := util.DocSlice(docs)
+	u := &util.BulkUploader{
+		Client:            c.dstC,
+		Path:              util.DatabasePath(c.dst) + "/_bulk_docs",
+		BatchSize:         len(docs),
+		Workers:           1,
+		PreserveRevisions: true,
+		OnResult: func(doc json.RawMessage, result util.BulkResult) error {
+			if result.Error == "" {
+				return nil
+			}
+			failed++
+			return errors.WrapExitError(chttp.ExitWriteError, enc.Encode(copyReject{BulkResult: result, Doc: doc}))
+		},
+	}
+	_, err := u.Upload(ctx, &src)
+	return failed, err
+}
@@ -0,0 +1,175 @@
+package database
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/copy"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+type copyResponse struct {
+	status int
+	body   string
+}
+
+// copyServer responds to requests, keyed by method and request URI, and
+// records them along with their bodies.
+type copyServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses map[string]copyResponse
+	requests  []string
+}
+
+func newCopyServer(t *testing.T) *copyServer {
+	s := &copyServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		key := r.Method + " " + r.URL.RequestURI()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, key+" "+string(body))
+		res, ok := s.responses[key]
+		if !ok {
+			t.Errorf("Unexpected request: %s", key)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(res.status)
+		_, _ = w.Write([]byte(res.body))
+	}))
+	return s
+}
+
+func TestCopyDatabaseCmd(t *testing.T) {
+	type cdTest struct {
+		test.CmdTest
+		s        *copyServer
+		requests []string
+	}
+	tests := testy.NewTable()
+	tests.Add("missing destination", cdTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost/foo"},
+			Err:    "accepts 2 arg(s), received 1",
+			Status: chttp.ExitUnknownFailure,
+		},
+	})
+	tests.Add("invalid batch size", cdTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost/foo", "http://localhost/bar", "--" + flagBatchSize, "0"},
+			Err:    "--batch-size must be a positive integer",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("copy", func(t *testing.T) interface{} {
+		s := newCopyServer(t)
+		tests.Cleanup(s.Close)
+		cpID := checkpointID(&kouch.Target{Root: s.URL, Database: "src"}, &kouch.Target{Root: s.URL, Database: "dst"})
+		s.responses = map[string]copyResponse{
+			"GET /dst/" + cpID: {http.StatusNotFound, `{"error":"not_found","reason":"missing"}`},
+			"GET /src/_changes?limit=100&since=0&style=all_docs":                {http.StatusOK, `{"results":[{"seq":"1-a","id":"foo","changes":[{"rev":"2-b"},{"rev":"2-c"}]},{"seq":"2-a","id":"bar","changes":[{"rev":"1-x"}]}],"last_seq":"2-a"}`},
+			"POST /dst/_revs_diff":                                              {http.StatusOK, `{"foo":{"missing":["2-c"]}}`},
+			"GET /src/foo?attachments=true&open_revs=%5B%222-c%22%5D&revs=true": {http.StatusOK, `[{"ok":{"_id":"foo","_rev":"2-c","_revisions":{"start":2,"ids":["c","a"]}}}]`},
+			"POST /dst/_bulk_docs":                                              {http.StatusCreated, `[]`},
+			"PUT /dst/" + cpID:                                                  {http.StatusCreated, `{"ok":true,"id":"` + cpID + `","rev":"0-1"}`},
+		}
+		return cdTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/src", s.URL + "/dst"},
+				Stdout: `{"changes":2,"failed":0,"last_seq":"2-a","revisions":1}` + "\n",
+			},
+			s: s,
+			requests: []string{
+				"GET /dst/" + cpID + " ",
+				"GET /src/_changes?limit=100&since=0&style=all_docs ",
+				`POST /dst/_revs_diff {"bar":["1-x"],"foo":["2-b","2-c"]}` + "\n",
+				"GET /src/foo?attachments=true&open_revs=%5B%222-c%22%5D&revs=true ",
+				`POST /dst/_bulk_docs {"docs":[{"_id":"foo","_rev":"2-c","_revisions":{"start":2,"ids":["c","a"]}}],"new_edits":false}` + "\n",
+				`PUT /dst/` + cpID + ` {"_id":"` + cpID + `","source":"` + s.URL + `/src","target":"` + s.URL + `/dst","last_seq":"2-a"}` + "\n",
+			},
+		}
+	})
+	tests.Add("resume from checkpoint, named context", func(t *testing.T) interface{} {
+		s := newCopyServer(t)
+		tests.Cleanup(s.Close)
+		conf := copyConfig(t, s.URL)
+		tests.Cleanup(func() { _ = os.Remove(conf) })
+		cpID := checkpointID(&kouch.Target{Root: s.URL, Database: "src"}, &kouch.Target{Root: s.URL, Database: "dst"})
+		s.responses = map[string]copyResponse{
+			"GET /dst/" + cpID: {http.StatusOK, `{"_id":"` + cpID + `","_rev":"0-1","last_seq":"2-a"}`},
+			"GET /src/_changes?limit=100&since=2-a&style=all_docs": {http.StatusOK, `{"results":[],"last_seq":"2-a"}`},
+		}
+		return cdTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{"--" + kouch.FlagConfigFile, conf, "foo/src", s.URL + "/dst"},
+				Stdout: `{"changes":0,"failed":0,"last_seq":"2-a","revisions":0}` + "\n",
+			},
+			s: s,
+			requests: []string{
+				"GET /dst/" + cpID + " ",
+				"GET /src/_changes?limit=100&since=2-a&style=all_docs ",
+			},
+		}
+	})
+	tests.Add("rejected revision", func(t *testing.T) interface{} {
+		s := newCopyServer(t)
+		tests.Cleanup(s.Close)
+		cpID := checkpointID(&kouch.Target{Root: s.URL, Database: "src"}, &kouch.Target{Root: s.URL, Database: "dst"})
+		s.responses = map[string]copyResponse{
+			"GET /dst/" + cpID: {http.StatusNotFound, `{"error":"not_found","reason":"missing"}`},
+			"GET /src/_changes?limit=100&since=0&style=all_docs":                {http.StatusOK, `{"results":[{"seq":"1-a","id":"foo","changes":[{"rev":"1-a"}]}],"last_seq":"1-a"}`},
+			"POST /dst/_revs_diff":                                              {http.StatusOK, `{"foo":{"missing":["1-a"]}}`},
+			"GET /src/foo?attachments=true&open_revs=%5B%221-a%22%5D&revs=true": {http.StatusOK, `[{"ok":{"_id":"foo","_rev":"1-a"}}]`},
+			"POST /dst/_bulk_docs":                                              {http.StatusCreated, `[{"id":"foo","error":"forbidden","reason":"Not allowed"}]`},
+		}
+		return cdTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/src", s.URL + "/dst"},
+				Stdout: `{"changes":1,"failed":1,"last_seq":0,"revisions":1}` + "\n",
+				Stderr: `{"id":"foo","error":"forbidden","reason":"Not allowed","doc":{"_id":"foo","_rev":"1-a"}}` + "\n",
+				Err:    "1 of 1 document revisions failed to copy",
+				Status: chttp.ExitPostError,
+			},
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt cdTest) {
+		test.ValidateCmdTest([]string{"copy", "database"})(t, tt.CmdTest)
+		if tt.s == nil {
+			return
+		}
+		tt.s.mu.Lock()
+		defer tt.s.mu.Unlock()
+		if d := diff.Interface(tt.requests, tt.s.requests); d != nil {
+			t.Errorf("Unexpected requests:\n%s", d)
+		}
+	})
+}
+
+// copyConfig writes a config file with a single context named foo, which is
+// not the default, and returns the filename.
+func copyConfig(t *testing.T, root string) string {
+	f, err := ioutil.TempFile("", "kouch-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close() // nolint: errcheck
+	conf := "contexts:\n- name: foo\n  context:\n    root: " + root + "\n"
+	if _, err := f.Write([]byte(conf)); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
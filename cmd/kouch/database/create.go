@@ -2,10 +2,14 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/go-kivik/couchdb/chttp"
 	"github.com/go-kivik/kouch"
 	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
 	"github.com/go-kivik/kouch/internal/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -24,24 +28,61 @@ func createDbCmd() *cobra.Command {
 			kouch.TargetHelpText(kouch.TargetDatabase),
 		RunE: createDatabaseCmd,
 	}
-	cmd.Flags().IntP(kouch.FlagShards, kouch.FlagShortShards, 0, "Shards, aka the number of range partitions.")
+	f := cmd.Flags()
+	AddCreateFlags(f)
+	f.BoolP(kouch.FlagYes, kouch.FlagShortYes, false, "Do not prompt for confirmation when using a protected context.")
 	return cmd
 }
 
+// AddCreateFlags adds the flags used to configure a new database.
+func AddCreateFlags(f *pflag.FlagSet) {
+	f.IntP(kouch.FlagShards, kouch.FlagShortShards, 0, "Shards, aka the number of range partitions.")
+	f.IntP(kouch.FlagReplicas, kouch.FlagShortReplicas, 0, "Replicas, aka the number of copies of every document.")
+	f.Bool(kouch.FlagPartitioned, false, "Create a partitioned database.")
+	f.String(kouch.FlagPlacement, "", "Placement rule, in the format `zone:replicas[,zone:replicas...]`. Overrides the default replica placement.")
+}
+
 func createDatabaseCmd(cmd *cobra.Command, _ []string) error {
 	ctx := kouch.GetContext(cmd)
-	o, err := createDatabaseOpts(ctx, cmd.Flags())
+	o, err := CreateDatabaseOpts(ctx, cmd.Flags())
 	if err != nil {
 		return err
 	}
+	if err := util.ConfirmMutation(o, cmd.Flags(), fmt.Sprintf("You are about to create the database '%s'.", o.Database), o.Database); err != nil {
+		return err
+	}
 	return util.ChttpDo(ctx, http.MethodPut, util.DatabasePath(o), o)
 }
 
-func createDatabaseOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+// CreateDatabaseOpts returns the options to create the target database,
+// based on the flags added by AddCreateFlags.
+func CreateDatabaseOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
 	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
 
-	if e := o.SetParam(flags, kouch.FlagShards); e != nil {
+	if e := o.SetParams(flags,
+		kouch.FlagShards, kouch.FlagReplicas, kouch.FlagPartitioned,
+		kouch.FlagPlacement,
+	); e != nil {
 		return nil, e
 	}
-	return o, err
+	if e := validateCreateParams(o); e != nil {
+		return nil, e
+	}
+	return o, nil
+}
+
+// validateCreateParams checks for incompatible combinations of database
+// creation parameters.
+func validateCreateParams(o *kouch.Options) error {
+	query := o.Query()
+	if query.Get("n") != "" && query.Get("placement") != "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "Must not use --%s and --%s together", kouch.FlagReplicas, kouch.FlagPlacement)
+	}
+	if query.Get("partitioned") == "true" && strings.HasPrefix(o.Database, "_") {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "System databases cannot be partitioned")
+	}
+	return nil
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/go-kivik/kouch"
 	"github.com/go-kivik/kouch/internal/test"
 
+	_ "github.com/go-kivik/kouch/cmd/kouch/copy"
 	_ "github.com/go-kivik/kouch/cmd/kouch/create"
 	_ "github.com/go-kivik/kouch/cmd/kouch/root"
 )
@@ -59,6 +60,58 @@ func TestCreateDatabaseCmd(t *testing.T) {
 			Stdout: `{"ok":true}`,
 		}
 	})
+	tests.Add("replicas, partitioned", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 201,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"ok":true}`)),
+		}, func(t *testing.T, req *http.Request) {
+			expected := test.NewRequest(t, "PUT", s.URL+"/oink?n=2&partitioned=true&q=5", nil)
+			expected.Header.Set("Content-Length", "0")
+			test.CheckRequest(t, expected, req)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/oink", "--" + kouch.FlagShards, "5", "--" + kouch.FlagReplicas, "2", "--" + kouch.FlagPartitioned},
+			Stdout: `{"ok":true}`,
+		}
+	})
+	tests.Add("placement", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 201,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"ok":true}`)),
+		}, func(t *testing.T, req *http.Request) {
+			expected := test.NewRequest(t, "PUT", s.URL+"/oink?placement=metro-a%3A2%2Cmetro-b%3A1", nil)
+			expected.Header.Set("Content-Length", "0")
+			test.CheckRequest(t, expected, req)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/oink", "--" + kouch.FlagPlacement, "metro-a:2,metro-b:1"},
+			Stdout: `{"ok":true}`,
+		}
+	})
+	tests.Add("invalid shards", test.CmdTest{
+		Args:   []string{"http://localhost/oink", "--" + kouch.FlagShards, "-1"},
+		Err:    "Invalid value for --" + kouch.FlagShards + ". Must be a positive integer",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid placement", test.CmdTest{
+		Args:   []string{"http://localhost/oink", "--" + kouch.FlagPlacement, "metro-a"},
+		Err:    "Invalid value for --" + kouch.FlagPlacement + ". Expected format: `zone:replicas[,zone:replicas...]`",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("replicas and placement", test.CmdTest{
+		Args:   []string{"http://localhost/oink", "--" + kouch.FlagReplicas, "2", "--" + kouch.FlagPlacement, "metro-a:2"},
+		Err:    "Must not use --" + kouch.FlagReplicas + " and --" + kouch.FlagPlacement + " together",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("partitioned system db", test.CmdTest{
+		Args:   []string{"http://localhost/_users", "--" + kouch.FlagPartitioned},
+		Err:    "System databases cannot be partitioned",
+		Status: chttp.ExitFailedToInitialize,
+	})
 	tests.Add("auth in target", func(t *testing.T) interface{} {
 		var s *httptest.Server
 		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
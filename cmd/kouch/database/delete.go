@@ -2,10 +2,14 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/go-kivik/couchdb/chttp"
 	"github.com/go-kivik/kouch"
 	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
 	"github.com/go-kivik/kouch/internal/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -24,6 +28,9 @@ func deleteDbCmd() *cobra.Command {
 			kouch.TargetHelpText(kouch.TargetDatabase),
 		RunE: deleteDatabaseCmd,
 	}
+	f := cmd.Flags()
+	f.BoolP(kouch.FlagYes, kouch.FlagShortYes, false, "Do not prompt for confirmation.")
+	f.Bool(kouch.FlagForceSystem, false, "Allow deletion of system databases (those beginning with '_').")
 	return cmd
 }
 
@@ -33,9 +40,32 @@ func deleteDatabaseCmd(cmd *cobra.Command, _ []string) error {
 	if err != nil {
 		return err
 	}
+	if err := confirmDelete(o, cmd.Flags()); err != nil {
+		return err
+	}
 	return util.ChttpDo(ctx, http.MethodDelete, util.DatabasePath(o), o)
 }
 
 func deleteDatabaseOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
 	return util.CommonOptions(ctx, kouch.TargetDatabase, flags)
 }
+
+// confirmDelete refuses to delete system databases without --force-system, and
+// requests confirmation when running interactively, or against a protected
+// context.
+func confirmDelete(o *kouch.Options, flags *pflag.FlagSet) error {
+	if strings.HasPrefix(o.Database, "_") {
+		force, err := flags.GetBool(kouch.FlagForceSystem)
+		if err != nil {
+			return err
+		}
+		if !force {
+			return errors.NewExitError(chttp.ExitFailedToInitialize, "Refusing to delete system database '%s' without --%s", o.Database, kouch.FlagForceSystem)
+		}
+	}
+	if !o.Protected && !util.IsTerminal() {
+		return nil
+	}
+	prompt := fmt.Sprintf("You are about to delete the database '%s'. This cannot be undone.", o.Database)
+	return util.Confirm(flags, prompt, o.Database)
+}
@@ -5,12 +5,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/flimzy/testy"
 	"github.com/go-kivik/couchdb/chttp"
 	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kouch"
 	"github.com/go-kivik/kouch/internal/test"
 
 	_ "github.com/go-kivik/kouch/cmd/kouch/delete"
@@ -39,6 +41,52 @@ func TestDeleteDatabaseCmd(t *testing.T) {
 			Stdout: `{"ok":true}`,
 		}
 	})
+	tests.Add("system database", test.CmdTest{
+		Args:   []string{"http://localhost/_users"},
+		Err:    "Refusing to delete system database '_users' without --" + kouch.FlagForceSystem,
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("force system database", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"ok":true}`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "DELETE", s.URL+"/_users", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/_users", "--" + kouch.FlagForceSystem},
+			Stdout: `{"ok":true}`,
+		}
+	})
+	tests.Add("protected context", func(t *testing.T) interface{} {
+		conf := protectedConfig(t, "http://localhost/")
+		tests.Cleanup(func() { _ = os.Remove(conf) })
+		return test.CmdTest{
+			Args:   []string{"--" + kouch.FlagConfigFile, conf, "oink"},
+			Err:    "Confirmation required; use --" + kouch.FlagYes + " to proceed non-interactively",
+			Status: chttp.ExitFailedToInitialize,
+		}
+	})
+	tests.Add("protected context, --yes", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"ok":true}`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "DELETE", s.URL+"/oink", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		conf := protectedConfig(t, s.URL)
+		tests.Cleanup(func() { _ = os.Remove(conf) })
+		return test.CmdTest{
+			Args:   []string{"--" + kouch.FlagConfigFile, conf, "oink", "--" + kouch.FlagYes},
+			Stdout: `{"ok":true}`,
+		}
+	})
 	tests.Add("auth in target", func(t *testing.T) interface{} {
 		var s *httptest.Server
 		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -61,3 +109,18 @@ func TestDeleteDatabaseCmd(t *testing.T) {
 
 	tests.Run(t, test.ValidateCmdTest([]string{"delete", "database"}))
 }
+
+// protectedConfig writes a config file with a single, protected, context, and
+// returns the filename.
+func protectedConfig(t *testing.T, root string) string {
+	f, err := ioutil.TempFile("", "kouch-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close() // nolint: errcheck
+	conf := "default-context: foo\ncontexts:\n- name: foo\n  context:\n    root: " + root + "\n    protected: true\n"
+	if _, err := f.Write([]byte(conf)); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
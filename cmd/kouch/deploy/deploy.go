@@ -0,0 +1,17 @@
+package deploy
+
+import (
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registry.Register(nil, deployCmd)
+}
+
+func deployCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "deploy",
+		Short: "Deploy a resource to the server.",
+	}
+}
@@ -0,0 +1,139 @@
+package design
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// jsonObject is a JSON object, with its members in their original order, as
+// CouchDB collates objects member by member.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	key   string
+	value interface{}
+}
+
+// decodeKey decodes a JSON view key for collation. Objects are decoded as
+// jsonObject, and numbers as float64.
+func decodeKey(raw json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	return decodeValue(dec)
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('['):
+		array := []interface{}{}
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err := dec.Token()
+		return array, err
+	case json.Delim('{'):
+		obj := jsonObject{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, jsonMember{key: key.(string), value: value})
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim(']'), json.Delim('}'):
+		return nil, io.ErrUnexpectedEOF
+	}
+	return tok, nil
+}
+
+// collator compares strings as CouchDB does, using the Unicode Collation
+// Algorithm. It is not safe for concurrent use.
+var collator = collate.New(language.Und)
+
+// collateKeys compares two decoded view keys in CouchDB's view collation
+// order: null, false, true, numbers, strings, arrays, then objects. It returns
+// -1, 0 or 1.
+func collateKeys(a, b interface{}) int {
+	if ra, rb := keyRank(a), keyRank(b); ra != rb {
+		return compareInts(ra, rb)
+	}
+	switch av := a.(type) {
+	case float64:
+		bv := b.(float64)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+		return 0
+	case string:
+		return collator.CompareString(av, b.(string))
+	case []interface{}:
+		bv := b.([]interface{})
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if c := collateKeys(av[i], bv[i]); c != 0 {
+				return c
+			}
+		}
+		return compareInts(len(av), len(bv))
+	case jsonObject:
+		bv := b.(jsonObject)
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if c := collator.CompareString(av[i].key, bv[i].key); c != 0 {
+				return c
+			}
+			if c := collateKeys(av[i].value, bv[i].value); c != 0 {
+				return c
+			}
+		}
+		return compareInts(len(av), len(bv))
+	}
+	return 0
+}
+
+func keyRank(v interface{}) int {
+	switch t := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if !t {
+			return 1
+		}
+		return 2
+	case float64:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	}
+	return 6
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,33 @@
+package design
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCollateKeys(t *testing.T) {
+	// Keys in CouchDB's collation order, from the view collation
+	// documentation.
+	keys := []string{
+		`null`, `false`, `true`,
+		`1`, `2`, `3.0`, `4`,
+		`"a"`, `"A"`, `"aa"`, `"b"`, `"B"`, `"ba"`, `"bb"`,
+		`["a"]`, `["b"]`, `["b","c"]`, `["b","c","a"]`, `["b","d"]`, `["b","d","e"]`,
+		`{"a":1}`, `{"a":2}`, `{"b":1}`, `{"b":2}`, `{"b":2,"a":1}`, `{"b":2,"c":2}`,
+	}
+	decoded := make([]interface{}, len(keys))
+	for i, key := range keys {
+		var err error
+		if decoded[i], err = decodeKey(json.RawMessage(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range keys {
+		for j := range keys {
+			expected := compareInts(i, j)
+			if c := collateKeys(decoded[i], decoded[j]); c != expected {
+				t.Errorf("collateKeys(%s, %s) = %d, expected %d", keys[i], keys[j], c, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package design
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/icza/dyno"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Special entries in a couchapp-style source directory.
+const (
+	fileID         = "_id"
+	dirAttachments = "_attachments"
+)
+
+const defaultContentType = "application/octet-stream"
+
+// Formats in which fields other than functions are written to a source tree.
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+)
+
+// functionSections are the design document fields whose members are
+// functions, each written to a .js file.
+var functionSections = map[string]bool{
+	"filters": true,
+	"lists":   true,
+	"shows":   true,
+	"updates": true,
+}
+
+// readDesignDir assembles a design document from a couchapp-style source
+// directory. Each sub-directory becomes an object, `.json` and `.yaml` files
+// are parsed, and any other file, such as `views/foo/map.js`, becomes a
+// string keyed by its name without the extension. Files in _attachments are
+// included as inline attachments.
+func readDesignDir(dir string) (map[string]interface{}, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	if !info.IsDir() {
+		return nil, errors.NewExitError(chttp.ExitReadError, "%s is not a directory", dir)
+	}
+	doc, err := readObject(dir)
+	if err != nil {
+		return nil, err
+	}
+	id, err := designDocID(dir)
+	if err != nil {
+		return nil, err
+	}
+	doc["_id"] = id
+	delete(doc, "_rev")
+	atts, err := readAttachments(filepath.Join(dir, dirAttachments))
+	if err != nil {
+		return nil, err
+	}
+	if len(atts) > 0 {
+		doc["_attachments"] = atts
+	}
+	return doc, nil
+}
+
+// designDocID returns the document ID from the _id file in dir, if there is
+// one, or else from the name of the directory.
+func designDocID(dir string) (string, error) {
+	id := filepath.Base(dir)
+	if abs, err := filepath.Abs(dir); err == nil {
+		id = filepath.Base(abs)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, fileID))
+	switch {
+	case err == nil:
+		id = strings.TrimSpace(string(content))
+	case !os.IsNotExist(err):
+		return "", errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	if !strings.HasPrefix(id, "_design/") {
+		id = "_design/" + id
+	}
+	return id, nil
+}
+
+// readObject reads the contents of dir into an object.
+func readObject(dir string) (map[string]interface{}, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	obj := make(map[string]interface{}, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if skipEntry(name) {
+			continue
+		}
+		key, value, err := readEntry(filepath.Join(dir, name), entry)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := obj[key]; ok {
+			return nil, errors.NewExitError(chttp.ExitReadError, "Conflicting entries for '%s' in %s", key, dir)
+		}
+		obj[key] = value
+	}
+	return obj, nil
+}
+
+// skipEntry returns true for hidden files, and the special entries which are
+// not read as document fields.
+func skipEntry(name string) bool {
+	return strings.HasPrefix(name, ".") || name == fileID || name == dirAttachments
+}
+
+func readEntry(filename string, info os.FileInfo) (string, interface{}, error) {
+	name := info.Name()
+	if info.IsDir() {
+		obj, err := readObject(filename)
+		return name, obj, err
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", nil, errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	ext := filepath.Ext(name)
+	key := strings.TrimSuffix(name, ext)
+	var value interface{}
+	switch ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		if err := dec.Decode(&value); err != nil {
+			return "", nil, errors.NewExitError(chttp.ExitReadError, "%s: %s", filename, err)
+		}
+	case ".yaml", ".yml":
+		var v interface{}
+		if err := yaml.Unmarshal(content, &v); err != nil {
+			return "", nil, errors.NewExitError(chttp.ExitReadError, "%s: %s", filename, err)
+		}
+		value = dyno.ConvertMapI2MapS(v)
+	default:
+		value = string(content)
+	}
+	return key, value, nil
+}
+
+// readAttachments reads all files below dir as inline attachments, named by
+// their slash-separated path relative to dir.
+func readAttachments(dir string) (map[string]interface{}, error) {
+	atts := make(map[string]interface{})
+	err := filepath.Walk(dir, func(filename string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && filename == dir {
+				return nil
+			}
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && filename != dir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, filename)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		atts[filepath.ToSlash(rel)] = map[string]interface{}{
+			"content_type": contentType(rel),
+			"data":         base64.StdEncoding.EncodeToString(content),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	return atts, nil
+}
+
+func contentType(filename string) string {
+	if ct := mime.TypeByExtension(path.Ext(filename)); ct != "" {
+		return ct
+	}
+	return defaultContentType
+}
+
+// digest calculates the attachment digest in the format used by CouchDB.
+func digest(content []byte) string {
+	sum := md5.Sum(content)
+	return "md5-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// designDocChanged returns true if the local design document differs from the
+// remote one, ignoring the revision. Attachments are compared by name,
+// media type and digest.
+func designDocChanged(local, remote map[string]interface{}) bool {
+	return !reflect.DeepEqual(normalizeDoc(local), normalizeDoc(remote))
+}
+
+// normalizeDoc returns a copy of doc suitable for comparison, with the
+// revision removed, attachments reduced to their media type and digest, and
+// values normalized through a JSON round trip. The digest of inline
+// attachments is calculated from their data.
+func normalizeDoc(doc map[string]interface{}) interface{} {
+	c := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		c[k] = v
+	}
+	delete(c, "_rev")
+	if atts, ok := c["_attachments"].(map[string]interface{}); ok {
+		stubs := make(map[string]interface{}, len(atts))
+		for name, att := range atts {
+			a, _ := att.(map[string]interface{})
+			stubs[name] = map[string]interface{}{
+				"content_type": mediaType(a["content_type"]),
+				"digest":       attachmentDigest(a),
+			}
+		}
+		c["_attachments"] = stubs
+	}
+	buf, err := json.Marshal(c)
+	if err != nil {
+		return c
+	}
+	var result interface{}
+	_ = json.Unmarshal(buf, &result)
+	return result
+}
+
+// mediaType strips any parameters, such as the charset, from a content type.
+func mediaType(contentType interface{}) interface{} {
+	ct, _ := contentType.(string)
+	if mt, _, err := mime.ParseMediaType(ct); err == nil {
+		return mt
+	}
+	return contentType
+}
+
+func attachmentDigest(att map[string]interface{}) interface{} {
+	data, ok := att["data"].(string)
+	if !ok {
+		return att["digest"]
+	}
+	content, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil
+	}
+	return digest(content)
+}
+
+// layoutWriter explodes a design document into a couchapp-style source
+// directory, the reverse of readDesignDir.
+type layoutWriter struct {
+	dir     string
+	format  string
+	clobber bool
+	// files lists the files written, relative to dir.
+	files []string
+}
+
+func (w *layoutWriter) write(doc map[string]interface{}) error {
+	id, _ := doc["_id"].(string)
+	if err := w.writeFile(fileID, []byte(id+"\n")); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(doc) {
+		switch key {
+		case "_id", "_rev":
+			continue
+		case "_attachments":
+			if err := w.writeAttachments(doc[key]); err != nil {
+				return err
+			}
+			continue
+		}
+		if !safeName(key) {
+			return errors.NewExitError(chttp.ExitWriteError, "Field '%s' cannot be written to a file", key)
+		}
+		if err := w.writeValue(nil, key, doc[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeValue writes functions to .js files, and the members of well-known
+// sections, such as views, to sub-directories. Any other value is encoded in
+// the configured format.
+func (w *layoutWriter) writeValue(parents []string, key string, value interface{}) error {
+	fieldPath := append(parents[:len(parents):len(parents)], key)
+	name := path.Join(fieldPath...)
+	if fn, ok := value.(string); ok && isFunction(fieldPath) {
+		return w.writeFile(name+".js", []byte(fn))
+	}
+	if obj, ok := value.(map[string]interface{}); ok && isSection(fieldPath) && safeNames(obj) {
+		if err := os.MkdirAll(filepath.Join(w.dir, filepath.FromSlash(name)), os.ModePerm); err != nil {
+			return errors.WrapExitError(chttp.ExitWriteError, err)
+		}
+		for _, k := range sortedKeys(obj) {
+			if err := w.writeValue(fieldPath, k, obj[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	content, err := w.encode(value)
+	if err != nil {
+		return err
+	}
+	return w.writeFile(name+"."+w.format, content)
+}
+
+func (w *layoutWriter) encode(value interface{}) ([]byte, error) {
+	if w.format == formatYAML {
+		content, err := yaml.Marshal(value)
+		return content, errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	return append(content, '\n'), nil
+}
+
+func (w *layoutWriter) writeAttachments(value interface{}) error {
+	atts, _ := value.(map[string]interface{})
+	for _, name := range sortedKeys(atts) {
+		att, _ := atts[name].(map[string]interface{})
+		data, ok := att["data"].(string)
+		if !ok {
+			return errors.NewExitError(chttp.ExitWeirdReply, "No data received for attachment '%s'", name)
+		}
+		content, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return errors.WrapExitError(chttp.ExitWeirdReply, err)
+		}
+		clean := path.Clean("/" + name)[1:]
+		if clean == "" || clean != name {
+			return errors.NewExitError(chttp.ExitWriteError, "Attachment '%s' cannot be written to a file", name)
+		}
+		if err := w.writeFile(dirAttachments+"/"+clean, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile writes content to name, a slash-separated path relative to the
+// target directory. Existing files are only overwritten when clobber is set.
+func (w *layoutWriter) writeFile(name string, content []byte) error {
+	filename := filepath.Join(w.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !w.clobber {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(filename, flags, 0666)
+	if err != nil {
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	if err := f.Close(); err != nil {
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	w.files = append(w.files, name)
+	return nil
+}
+
+// isFunction returns true if the field at fieldPath holds a function.
+func isFunction(fieldPath []string) bool {
+	switch len(fieldPath) {
+	case 1:
+		return fieldPath[0] == "validate_doc_update"
+	case 2:
+		return functionSections[fieldPath[0]]
+	case 3:
+		return fieldPath[0] == "views" && (fieldPath[2] == "map" || fieldPath[2] == "reduce")
+	}
+	return false
+}
+
+// isSection returns true if the field at fieldPath is written as a directory.
+func isSection(fieldPath []string) bool {
+	switch len(fieldPath) {
+	case 1:
+		return fieldPath[0] == "views" || functionSections[fieldPath[0]]
+	case 2:
+		return fieldPath[0] == "views"
+	}
+	return false
+}
+
+// safeName returns true if name may be used as a file name, such that it will
+// be read back by readDesignDir.
+func safeName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\`) && !skipEntry(name)
+}
+
+func safeNames(obj map[string]interface{}) bool {
+	for name := range obj {
+		if !safeName(name) {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,337 @@
+package design
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagDryRun       = "dry-run"
+	flagPollInterval = "poll-interval"
+)
+
+const (
+	stagingSuffix       = "-staging"
+	defaultPollInterval = 5 * time.Second
+)
+
+func init() {
+	registry.Register([]string{"deploy"}, deployDesignCmd)
+}
+
+func deployDesignCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "design <dir> [db]",
+		Aliases: []string{"ddoc"},
+		Short:   "Deploys a design document, building its indexes before it goes live.",
+		Long: "Deploys a design document from a couchapp-style source directory, as used by 'kouch push design', without blocking queries while its views are built.\n\n" +
+			"A diff of the live and new definitions is first written to standard error. " +
+			"The new version is then stored as _design/{name}" + stagingSuffix + ", its index build is triggered, and _active_tasks is polled until the build is complete. " +
+			"Finally, the staging design document is copied over the live one, which then uses the already built index, and deleted.\n\n" +
+			"The database may be given as a name, or as a full URL, such as http://localhost:5984/db.",
+		Args:              cobra.RangeArgs(1, 2),
+		PersistentPreRunE: util.PositionalArgs,
+		RunE:              deployDesignDocCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided as the second argument.")
+	f.Bool(flagDryRun, false, "Show the diff, without deploying.")
+	f.Duration(flagPollInterval, defaultPollInterval, "How often to poll _active_tasks while the index is built.")
+	f.BoolP(kouch.FlagYes, kouch.FlagShortYes, false, "Do not prompt for confirmation when using a protected context.")
+	return cmd
+}
+
+type deployer struct {
+	o        *kouch.Options
+	c        *chttp.Client
+	doc      map[string]interface{}
+	dryRun   bool
+	interval time.Duration
+	// status receives the diff and progress messages.
+	status io.Writer
+}
+
+func deployDesignDocCmd(cmd *cobra.Command, args []string) error {
+	doc, err := readDesignDir(args[0])
+	if err != nil {
+		return err
+	}
+	ctx := kouch.GetContext(cmd)
+	d, err := deployDesignOpts(ctx, cmd.Flags(), doc, args[1:])
+	if err != nil {
+		return err
+	}
+	return d.deploy(ctx, cmd.Flags())
+}
+
+func deployDesignOpts(ctx context.Context, flags *pflag.FlagSet, doc map[string]interface{}, args []string) (*deployer, error) {
+	o, err := designDocOpts(ctx, flags, doc["_id"].(string), args)
+	if err != nil {
+		return nil, err
+	}
+	d := &deployer{
+		o:      o,
+		doc:    doc,
+		status: os.Stderr,
+	}
+	if d.dryRun, err = flags.GetBool(flagDryRun); err != nil {
+		return nil, err
+	}
+	if d.interval, err = flags.GetDuration(flagPollInterval); err != nil {
+		return nil, err
+	}
+	if d.interval <= 0 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must be positive", flagPollInterval)
+	}
+	if d.c, err = o.NewClient(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *deployer) deploy(ctx context.Context, flags *pflag.FlagSet) error {
+	live, err := fetchDesignDoc(ctx, d.o)
+	if err != nil {
+		return err
+	}
+	if live != nil && !designDocChanged(d.doc, live) {
+		_, _ = fmt.Fprintf(d.status, "Design document '%s' is unchanged\n", d.o.Document)
+		return writeResult(ctx, map[string]interface{}{
+			"ok":  true,
+			"id":  live["_id"],
+			"rev": live["_rev"],
+		})
+	}
+	if err := writeDiff(d.status, d.o.Document, live, d.doc); err != nil {
+		return err
+	}
+	if d.dryRun {
+		return nil
+	}
+	if err := util.ConfirmMutation(d.o, flags, fmt.Sprintf("You are about to deploy the design document '%s' to the database '%s'.", d.o.Document, d.o.Database), d.o.Database); err != nil {
+		return err
+	}
+	staging := d.stagingOpts()
+	stagingRev, err := d.putStaging(ctx, staging)
+	if err != nil {
+		return err
+	}
+	if err := d.buildIndex(ctx, staging); err != nil {
+		return err
+	}
+	copyOpts := *staging
+	copyOpts.Options = &chttp.Options{Destination: chttp.EncodeDocID(d.o.Document)}
+	if live != nil {
+		copyOpts.Destination += "?rev=" + live["_rev"].(string)
+	}
+	if err := util.ChttpDo(ctx, "COPY", util.DocPath(&copyOpts), &copyOpts); err != nil {
+		return err
+	}
+	if err := d.deleteStaging(ctx, staging, stagingRev); err != nil {
+		_, _ = fmt.Fprintf(d.status, "Warning: failed to delete %s: %s\n", staging.Document, err)
+	}
+	return nil
+}
+
+// stagingOpts returns options targeting the staging design document.
+func (d *deployer) stagingOpts() *kouch.Options {
+	t := *d.o.Target
+	t.Document += stagingSuffix
+	t.DesignDoc = strings.TrimPrefix(t.Document, "_design/")
+	o := kouch.NewOptions()
+	o.Target = &t
+	return o
+}
+
+// putStaging stores the new design document as the staging design document,
+// replacing any previous version, and returns the new revision.
+func (d *deployer) putStaging(ctx context.Context, staging *kouch.Options) (string, error) {
+	current, err := fetchDesignDoc(ctx, staging)
+	if err != nil {
+		return "", err
+	}
+	doc := make(map[string]interface{}, len(d.doc)+1)
+	for k, v := range d.doc {
+		doc[k] = v
+	}
+	doc["_id"] = staging.Document
+	if current != nil {
+		doc["_rev"] = current["_rev"]
+	}
+	var result struct {
+		Rev string `json:"rev"`
+	}
+	err = d.do(ctx, http.MethodPut, util.DocPath(staging), &chttp.Options{Body: chttp.EncodeBody(doc)}, &result)
+	return result.Rev, err
+}
+
+// buildIndex triggers the index build for the staging design document, and
+// waits for it to complete.
+func (d *deployer) buildIndex(ctx context.Context, staging *kouch.Options) error {
+	views, _ := d.doc["views"].(map[string]interface{})
+	if len(views) == 0 {
+		return nil
+	}
+	// All views in a design document share a single index, so querying one
+	// of them builds them all.
+	staging.View = sortedKeys(views)[0]
+	viewPath := util.ViewPath(staging)
+	// The index is up to date once it includes the staging design document.
+	target, err := d.updateSeq(ctx, util.DatabasePath(staging), nil)
+	if err != nil {
+		return err
+	}
+	lazy := &chttp.Options{Query: map[string][]string{"limit": {"0"}, "update": {"lazy"}}}
+	if err := d.do(ctx, http.MethodGet, viewPath, lazy, nil); err != nil {
+		return err
+	}
+	stale := &chttp.Options{Query: map[string][]string{"limit": {"0"}, "stale": {"ok"}, "update_seq": {"true"}}}
+	var seen bool
+	for {
+		progress, building, err := d.indexProgress(ctx, staging)
+		if err != nil {
+			return err
+		}
+		if building {
+			seen = true
+			_, _ = fmt.Fprintf(d.status, "Building index for %s: %d%%\n", staging.Document, progress)
+		} else if seen {
+			break
+		} else {
+			// The build has either not started yet, or already finished.
+			seq, err := d.updateSeq(ctx, viewPath, stale)
+			if err != nil {
+				return err
+			}
+			if seq >= target {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d.interval):
+		}
+	}
+	// The index should now be up to date, so this returns immediately; if
+	// not, it waits for the build to complete.
+	return d.do(ctx, http.MethodGet, viewPath, &chttp.Options{Query: map[string][]string{"limit": {"0"}}}, nil)
+}
+
+// updateSeq returns the numeric part of the update_seq in the response to a
+// GET request for path.
+func (d *deployer) updateSeq(ctx context.Context, path string, opts *chttp.Options) (int64, error) {
+	var result struct {
+		UpdateSeq json.RawMessage `json:"update_seq"`
+	}
+	if err := d.do(ctx, http.MethodGet, path, opts, &result); err != nil {
+		return 0, err
+	}
+	return seqNumber(result.UpdateSeq)
+}
+
+// seqNumber returns the numeric part of a sequence, which may be a JSON string
+// prefixed with a number, such as "12-g1AAAA" (CouchDB 2.x+), or a number
+// (CouchDB 1.x).
+func seqNumber(seq json.RawMessage) (int64, error) {
+	num := string(seq)
+	var s string
+	if err := json.Unmarshal(seq, &s); err == nil {
+		num = strings.SplitN(s, "-", 2)[0]
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil {
+		return 0, errors.NewExitError(chttp.ExitWeirdReply, "Unrecognized update sequence: %s", seq)
+	}
+	return n, nil
+}
+
+// indexProgress returns the average progress of the indexer tasks for the
+// staging design document, and whether there are any.
+func (d *deployer) indexProgress(ctx context.Context, staging *kouch.Options) (int, bool, error) {
+	var tasks []struct {
+		Type           string `json:"type"`
+		Database       string `json:"database"`
+		DesignDocument string `json:"design_document"`
+		Progress       int    `json:"progress"`
+	}
+	if err := d.do(ctx, http.MethodGet, "/_active_tasks", nil, &tasks); err != nil {
+		return 0, false, err
+	}
+	var count, total int
+	for _, task := range tasks {
+		if task.Type == "indexer" && task.DesignDocument == staging.Document && util.TaskDatabase(task.Database) == staging.Database {
+			count++
+			total += task.Progress
+		}
+	}
+	if count == 0 {
+		return 0, false, nil
+	}
+	return total / count, true, nil
+}
+
+func (d *deployer) deleteStaging(ctx context.Context, staging *kouch.Options, rev string) error {
+	return d.do(ctx, http.MethodDelete, util.DocPath(staging), &chttp.Options{Query: map[string][]string{"rev": {rev}}}, nil)
+}
+
+// do performs a request, and decodes the response into result, unless it is
+// nil.
+func (d *deployer) do(ctx context.Context, method, path string, opts *chttp.Options, result interface{}) error {
+	res, err := d.c.DoReq(ctx, method, path, opts)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close() // nolint: errcheck
+	if err = chttp.ResponseError(res); err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return errors.WrapExitError(chttp.ExitWeirdReply, err)
+	}
+	return nil
+}
+
+// writeDiff writes a unified diff of the live and new design documents to w.
+func writeDiff(w io.Writer, id string, live, doc map[string]interface{}) error {
+	var a []string
+	if live != nil {
+		a = difflib.SplitLines(indentJSON(normalizeDoc(live)))
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        a,
+		B:        difflib.SplitLines(indentJSON(normalizeDoc(doc))),
+		FromFile: id + " (live)",
+		ToFile:   id + " (new)",
+		Context:  3,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, diff)
+	return err
+}
+
+func indentJSON(v interface{}) string {
+	buf, _ := json.MarshalIndent(v, "", "  ")
+	return string(buf)
+}
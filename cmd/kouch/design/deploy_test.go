@@ -0,0 +1,248 @@
+package design
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/deploy"
+)
+
+// sequenceServer is like designServer, but responds to repeated requests for
+// the same path with successive responses, repeating the last one.
+func sequenceServer(t *testing.T, tests *testy.Table, responses map[string][]response) (*httptest.Server, *[]string) {
+	var requests []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		request := []string{r.Method, r.URL.RequestURI()}
+		for _, part := range []string{r.Header.Get("Destination"), strings.TrimSpace(string(body))} {
+			if part != "" {
+				request = append(request, part)
+			}
+		}
+		requests = append(requests, strings.Join(request, " "))
+		w.Header().Set("Content-Type", "application/json")
+		key := r.Method + " " + r.URL.Path
+		res := response{status: http.StatusNotFound, body: `{"error":"not_found","reason":"missing"}`}
+		if queue := responses[key]; len(queue) > 0 {
+			res = queue[0]
+			if len(queue) > 1 {
+				responses[key] = queue[1:]
+			}
+		}
+		w.WriteHeader(res.status)
+		_, _ = w.Write([]byte(res.body))
+	}))
+	tests.Cleanup(s.Close)
+	return s, &requests
+}
+
+func TestDeployDesignCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("invalid poll interval", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, appTree)
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, "http://localhost/foo", "--poll-interval", "0s"},
+				Err:    "--poll-interval must be positive",
+				Status: chttp.ExitFailedToInitialize,
+			},
+		}
+	})
+	tests.Add("unchanged", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, appTree)
+		s, requests := designServer(t, tests, map[string]response{
+			"GET /foo/_design/app": {http.StatusOK, appStub},
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, s.URL + "/foo"},
+				Stdout: `{"id":"_design/app","ok":true,"rev":"1-abc"}`,
+				Stderr: "Design document '_design/app' is unchanged\n",
+			},
+			requests: requests,
+			expected: []string{"GET /foo/_design/app"},
+		}
+	})
+	tests.Add("dry run", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{"views/a/map.js": "function(doc) { emit(doc.b); }"})
+		s, requests := designServer(t, tests, map[string]response{
+			"GET /foo/_design/app": {http.StatusOK, `{"_id":"_design/app","_rev":"1-abc","views":{"a":{"map":"function(doc) { emit(doc.a); }"}}}`},
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args: []string{dir, s.URL + "/foo", "--dry-run"},
+				Stderr: `--- _design/app (live)
++++ _design/app (new)
+@@ -2,7 +2,7 @@
+   "_id": "_design/app",
+   "views": {
+     "a": {
+-      "map": "function(doc) { emit(doc.a); }"
++      "map": "function(doc) { emit(doc.b); }"
+     }
+   }
+ }
+`,
+			},
+			requests: requests,
+			expected: []string{"GET /foo/_design/app"},
+		}
+	})
+	tests.Add("deploy", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{"views/a/map.js": "function(doc) { emit(doc.b); }"})
+		s, requests := sequenceServer(t, tests, map[string][]response{
+			"GET /foo/_design/app":                 {{http.StatusOK, `{"_id":"_design/app","_rev":"1-abc","views":{"a":{"map":"function(doc) { emit(doc.a); }"}}}`}},
+			"GET /foo/_design/app-staging":         {{http.StatusOK, `{"_id":"_design/app-staging","_rev":"3-old"}`}},
+			"PUT /foo/_design/app-staging":         {{http.StatusCreated, `{"ok":true,"id":"_design/app-staging","rev":"4-new"}`}},
+			"GET /foo":                             {{http.StatusOK, `{"db_name":"foo","update_seq":"12-g1AAAA"}`}},
+			"GET /foo/_design/app-staging/_view/a": {{http.StatusOK, `{"total_rows":0,"offset":0,"rows":[]}`}},
+			"GET /_active_tasks": {
+				{http.StatusOK, `[{"type":"indexer","database":"shards/00000000-7fffffff/foo.1530000000","design_document":"_design/app-staging","progress":20},{"type":"indexer","database":"shards/80000000-ffffffff/foo.1530000000","design_document":"_design/app-staging","progress":60},{"type":"indexer","database":"shards/00000000-ffffffff/bar.1530000000","design_document":"_design/app-staging","progress":0}]`},
+				{http.StatusOK, `[{"type":"replication"}]`},
+			},
+			"COPY /foo/_design/app-staging":   {{http.StatusCreated, `{"ok":true,"id":"_design/app","rev":"2-def"}`}},
+			"DELETE /foo/_design/app-staging": {{http.StatusOK, `{"ok":true,"id":"_design/app-staging","rev":"5-del"}`}},
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, s.URL + "/foo", "--poll-interval", "1ms"},
+				Stdout: `{"id":"_design/app","ok":true,"rev":"2-def"}`,
+				Stderr: `--- _design/app (live)
++++ _design/app (new)
+@@ -2,7 +2,7 @@
+   "_id": "_design/app",
+   "views": {
+     "a": {
+-      "map": "function(doc) { emit(doc.a); }"
++      "map": "function(doc) { emit(doc.b); }"
+     }
+   }
+ }
+Building index for _design/app-staging: 40%
+`,
+			},
+			requests: requests,
+			expected: []string{
+				"GET /foo/_design/app",
+				"GET /foo/_design/app-staging",
+				`PUT /foo/_design/app-staging {"_id":"_design/app-staging","_rev":"3-old","views":{"a":{"map":"function(doc) { emit(doc.b); }"}}}`,
+				"GET /foo",
+				"GET /foo/_design/app-staging/_view/a?limit=0&update=lazy",
+				"GET /_active_tasks",
+				"GET /_active_tasks",
+				"GET /foo/_design/app-staging/_view/a?limit=0",
+				"COPY /foo/_design/app-staging _design/app?rev=1-abc",
+				"DELETE /foo/_design/app-staging?rev=4-new",
+			},
+		}
+	})
+
+	tests.Add("indexer starts after first poll", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{"views/a/map.js": "function(doc) { emit(doc.b); }"})
+		s, requests := sequenceServer(t, tests, map[string][]response{
+			"PUT /foo/_design/app-staging": {{http.StatusCreated, `{"ok":true,"id":"_design/app-staging","rev":"1-new"}`}},
+			"GET /foo":                     {{http.StatusOK, `{"db_name":"foo","update_seq":"12-g1AAAA"}`}},
+			"GET /foo/_design/app-staging/_view/a": {
+				{http.StatusOK, `{"total_rows":0,"offset":0,"rows":[]}`},
+				{http.StatusOK, `{"total_rows":0,"offset":0,"rows":[],"update_seq":"10-g1AAAA"}`},
+				{http.StatusOK, `{"total_rows":0,"offset":0,"rows":[]}`},
+			},
+			"GET /_active_tasks": {
+				{http.StatusOK, `[]`},
+				{http.StatusOK, `[{"type":"indexer","database":"shards/00000000-ffffffff/foo.1530000000","design_document":"_design/app-staging","progress":50}]`},
+				{http.StatusOK, `[]`},
+			},
+			"COPY /foo/_design/app-staging":   {{http.StatusCreated, `{"ok":true,"id":"_design/app","rev":"1-def"}`}},
+			"DELETE /foo/_design/app-staging": {{http.StatusOK, `{"ok":true,"id":"_design/app-staging","rev":"2-del"}`}},
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, s.URL + "/foo", "--poll-interval", "1ms"},
+				Stdout: `{"id":"_design/app","ok":true,"rev":"1-def"}`,
+				Stderr: `--- _design/app (live)
++++ _design/app (new)
+@@ -0,0 +1,8 @@
++{
++  "_id": "_design/app",
++  "views": {
++    "a": {
++      "map": "function(doc) { emit(doc.b); }"
++    }
++  }
++}
+Building index for _design/app-staging: 50%
+`,
+			},
+			requests: requests,
+			expected: []string{
+				"GET /foo/_design/app",
+				"GET /foo/_design/app-staging",
+				`PUT /foo/_design/app-staging {"_id":"_design/app-staging","views":{"a":{"map":"function(doc) { emit(doc.b); }"}}}`,
+				"GET /foo",
+				"GET /foo/_design/app-staging/_view/a?limit=0&update=lazy",
+				"GET /_active_tasks",
+				"GET /foo/_design/app-staging/_view/a?limit=0&stale=ok&update_seq=true",
+				"GET /_active_tasks",
+				"GET /_active_tasks",
+				"GET /foo/_design/app-staging/_view/a?limit=0",
+				"COPY /foo/_design/app-staging _design/app",
+				"DELETE /foo/_design/app-staging?rev=1-new",
+			},
+		}
+	})
+	tests.Add("index already built", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{"views/a/map.js": "function(doc) { emit(doc.b); }"})
+		s, requests := sequenceServer(t, tests, map[string][]response{
+			"PUT /foo/_design/app-staging": {{http.StatusCreated, `{"ok":true,"id":"_design/app-staging","rev":"1-new"}`}},
+			"GET /foo":                     {{http.StatusOK, `{"db_name":"foo","update_seq":12}`}},
+			"GET /foo/_design/app-staging/_view/a": {
+				{http.StatusOK, `{"total_rows":0,"offset":0,"rows":[]}`},
+				{http.StatusOK, `{"total_rows":0,"offset":0,"rows":[],"update_seq":12}`},
+				{http.StatusOK, `{"total_rows":0,"offset":0,"rows":[]}`},
+			},
+			"GET /_active_tasks":              {{http.StatusOK, `[]`}},
+			"COPY /foo/_design/app-staging":   {{http.StatusCreated, `{"ok":true,"id":"_design/app","rev":"1-def"}`}},
+			"DELETE /foo/_design/app-staging": {{http.StatusOK, `{"ok":true,"id":"_design/app-staging","rev":"2-del"}`}},
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, s.URL + "/foo", "--poll-interval", "1ms"},
+				Stdout: `{"id":"_design/app","ok":true,"rev":"1-def"}`,
+				Stderr: `--- _design/app (live)
++++ _design/app (new)
+@@ -0,0 +1,8 @@
++{
++  "_id": "_design/app",
++  "views": {
++    "a": {
++      "map": "function(doc) { emit(doc.b); }"
++    }
++  }
++}
+`,
+			},
+			requests: requests,
+			expected: []string{
+				"GET /foo/_design/app",
+				"GET /foo/_design/app-staging",
+				`PUT /foo/_design/app-staging {"_id":"_design/app-staging","views":{"a":{"map":"function(doc) { emit(doc.b); }"}}}`,
+				"GET /foo",
+				"GET /foo/_design/app-staging/_view/a?limit=0&update=lazy",
+				"GET /_active_tasks",
+				"GET /foo/_design/app-staging/_view/a?limit=0&stale=ok&update_seq=true",
+				"GET /foo/_design/app-staging/_view/a?limit=0",
+				"COPY /foo/_design/app-staging _design/app",
+				"DELETE /foo/_design/app-staging?rev=1-new",
+			},
+		}
+	})
+
+	runDesignTests(t, tests, []string{"deploy", "design"})
+}
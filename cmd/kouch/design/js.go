@@ -0,0 +1,125 @@
+package design
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/robertkrimen/otto"
+)
+
+// jsPrelude defines the helpers which CouchDB's JavaScript query server makes
+// available to design document functions, and __kouchCall, which calls a
+// function with JSON-encoded arguments, and returns a JSON-encoded jsResult.
+const jsPrelude = `
+var __kouchEmitted = [];
+
+function emit(key, value) {
+	__kouchEmitted.push([key === undefined ? null : key, value === undefined ? null : value]);
+}
+
+function sum(values) {
+	var total = 0;
+	for (var i = 0; i < values.length; i++) {
+		total += values[i];
+	}
+	return total;
+}
+
+function toJSON(value) {
+	return JSON.stringify(value);
+}
+
+function isArray(value) {
+	return Array.isArray(value);
+}
+
+function log(message) {
+	__kouchLog(typeof message == "string" ? message : JSON.stringify(message));
+}
+
+function __kouchCall(fn, args) {
+	__kouchEmitted = [];
+	try {
+		var result = fn.apply(null, JSON.parse(args));
+		return JSON.stringify({result: result === undefined ? null : result, emitted: __kouchEmitted});
+	} catch (e) {
+		if (e instanceof Error) {
+			return JSON.stringify({error: String(e)});
+		}
+		return JSON.stringify({thrown: e === undefined ? null : e});
+	}
+}
+`
+
+// jsRuntime evaluates design document functions with an embedded JavaScript
+// interpreter.
+type jsRuntime struct {
+	vm   *otto.Otto
+	call otto.Value
+}
+
+// jsResult is the outcome of a single function call.
+type jsResult struct {
+	Result json.RawMessage `json:"result"`
+	// Emitted holds the key/value pairs passed to emit().
+	Emitted [][2]json.RawMessage `json:"emitted"`
+	// Error is set if the function threw an Error.
+	Error string `json:"error"`
+	// Thrown is set if the function threw any other value, as
+	// validate_doc_update does to reject a document.
+	Thrown json.RawMessage `json:"thrown"`
+}
+
+// newJSRuntime returns a new runtime. Messages passed to log() are written to
+// logW.
+func newJSRuntime(logW io.Writer) (*jsRuntime, error) {
+	vm := otto.New()
+	if err := vm.Set("__kouchLog", func(call otto.FunctionCall) otto.Value {
+		_, _ = fmt.Fprintln(logW, call.Argument(0).String())
+		return otto.UndefinedValue()
+	}); err != nil {
+		return nil, err
+	}
+	if _, err := vm.Run(jsPrelude); err != nil {
+		return nil, err
+	}
+	call, err := vm.Get("__kouchCall")
+	if err != nil {
+		return nil, err
+	}
+	return &jsRuntime{vm: vm, call: call}, nil
+}
+
+// compile evaluates src, which must be a function expression.
+func (r *jsRuntime) compile(src string) (otto.Value, error) {
+	fn, err := r.vm.Run("(" + src + "\n)")
+	if err != nil {
+		return otto.Value{}, err
+	}
+	if !fn.IsFunction() {
+		return otto.Value{}, fmt.Errorf("expected a function expression")
+	}
+	return fn, nil
+}
+
+// exec calls fn with args, each of which is encoded to JSON. An error is
+// returned if fn threw an Error.
+func (r *jsRuntime) exec(fn otto.Value, args ...interface{}) (*jsResult, error) {
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	value, err := r.call.Call(otto.NullValue(), fn, string(encoded))
+	if err != nil {
+		return nil, err
+	}
+	result := &jsResult{}
+	if err := json.Unmarshal([]byte(value.String()), result); err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("%s", result.Error)
+	}
+	return result, nil
+}
@@ -0,0 +1,297 @@
+package design
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	kio "github.com/go-kivik/kouch/io"
+	"github.com/spf13/cobra"
+)
+
+const flagStrict = "strict"
+
+// Finding severities
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// builtinReducers are the reduce functions implemented natively by CouchDB.
+var builtinReducers = map[string]bool{
+	"_sum":                   true,
+	"_count":                 true,
+	"_stats":                 true,
+	"_approx_count_distinct": true,
+}
+
+var (
+	nonDeterministicRE = regexp.MustCompile(`\bDate\.now\s*\(|\bnew\s+Date\s*\(\s*\)|\bMath\.random\s*\(`)
+	emitDocRE          = regexp.MustCompile(`\bemit\s*\([^;]*,\s*doc\s*\)`)
+	whitespaceRE       = regexp.MustCompile(`[\s;]+`)
+)
+
+// duplicateReducers maps the bodies of custom reduce functions, with
+// whitespace and semicolons removed, to the equivalent built-in reducer.
+var duplicateReducers = map[string]string{
+	"returnsum(values)":   "_sum",
+	"returnvalues.length": "_count",
+	"if(rereduce){returnsum(values)}returnvalues.length":       "_count",
+	"if(rereduce){returnsum(values)}else{returnvalues.length}": "_count",
+}
+
+func init() {
+	registry.Register([]string{"lint"}, lintDesignCmd)
+}
+
+func lintDesignCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "design <dir|target>",
+		Aliases: []string{"ddoc"},
+		Short:   "Checks a design document for common mistakes.",
+		Long: "Checks a design document, either from a couchapp-style source directory, as used by 'kouch push design', or from the server, for common mistakes.\n\n" +
+			"Each function is parsed, as ECMAScript 5, to check for syntax errors. " +
+			"Warnings are reported for map functions which emit the whole document or are not deterministic, " +
+			"and for custom reduce functions which duplicate a built-in reducer or do not handle rereduce. " +
+			"Mango index definitions, in design documents with the language `query`, are validated.\n\n" +
+			"Each finding is written as a separate document. " +
+			"The exit status is non-zero if any errors, or with --" + flagStrict + " any warnings, are found.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDocument),
+		Args:              cobra.ExactArgs(1),
+		PersistentPreRunE: util.PositionalArgs,
+		RunE:              lintDesignDocCmd,
+	}
+	f := cmd.Flags()
+	f.Bool(flagStrict, false, "Treat warnings as errors.")
+	return cmd
+}
+
+// finding is a single problem found in a design document.
+type finding struct {
+	Severity string `json:"severity"`
+	Field    string `json:"field"`
+	Message  string `json:"message"`
+}
+
+func lintDesignDocCmd(cmd *cobra.Command, args []string) error {
+	ctx := kouch.GetContext(cmd)
+	doc, err := lintSource(ctx, cmd, args[0])
+	if err != nil {
+		return err
+	}
+	strict, err := cmd.Flags().GetBool(flagStrict)
+	if err != nil {
+		return err
+	}
+	return reportFindings(ctx, lintDesignDoc(doc), strict)
+}
+
+// lintSource reads the design document from src, if it is a directory, or
+// else fetches it from the server.
+func lintSource(ctx context.Context, cmd *cobra.Command, src string) (map[string]interface{}, error) {
+	if info, err := os.Stat(src); err == nil && info.IsDir() {
+		return readDesignDir(src)
+	}
+	ctx = kouch.SetTarget(ctx, src)
+	o, err := util.CommonOptions(ctx, kouch.TargetDocument, cmd.Flags())
+	if err != nil {
+		return nil, err
+	}
+	if err := validateTarget(o.Target); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(o.Document, "_design/") {
+		o.Document = "_design/" + o.Document
+	}
+	doc, err := fetchDesignDoc(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, errors.NewExitError(chttp.ExitNotRetrieved, "Design document '%s' not found", o.Document)
+	}
+	return doc, nil
+}
+
+func reportFindings(ctx context.Context, findings []finding, strict bool) error {
+	w, err := kio.NewDocWriter(ctx)
+	if err != nil {
+		return err
+	}
+	var errs, warnings int
+	for _, f := range findings {
+		if f.Severity == severityError {
+			errs++
+		} else {
+			warnings++
+		}
+		doc, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		if err := w.WriteDoc(doc); err != nil {
+			return err
+		}
+	}
+	if errs > 0 || (strict && warnings > 0) {
+		return errors.NewExitError(chttp.ExitUnknownFailure, "Found %d error(s) and %d warning(s)", errs, warnings)
+	}
+	return nil
+}
+
+// lintDesignDoc checks doc, and returns any findings.
+func lintDesignDoc(doc map[string]interface{}) []finding {
+	l := &linter{}
+	if lang, _ := doc["language"].(string); lang == "query" {
+		l.lintIndexes(doc["views"])
+		return l.findings
+	}
+	views, _ := doc["views"].(map[string]interface{})
+	for _, name := range sortedKeys(views) {
+		view, ok := views[name].(map[string]interface{})
+		if !ok {
+			l.errorf("views/"+name, "View definition must be an object")
+			continue
+		}
+		field := "views/" + name
+		if l.lintFunction(field+"/map", view["map"], true) {
+			l.lintMap(field+"/map", view["map"].(string))
+		}
+		if reduce, ok := view["reduce"]; ok {
+			l.lintReduce(field+"/reduce", reduce)
+		}
+	}
+	if fn, ok := doc["validate_doc_update"]; ok {
+		l.lintFunction("validate_doc_update", fn, false)
+	}
+	sections := make([]string, 0, len(functionSections))
+	for section := range functionSections {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+	for _, section := range sections {
+		funcs, _ := doc[section].(map[string]interface{})
+		for _, name := range sortedKeys(funcs) {
+			l.lintFunction(section+"/"+name, funcs[name], false)
+		}
+	}
+	return l.findings
+}
+
+type linter struct {
+	findings []finding
+}
+
+func (l *linter) errorf(field, format string, args ...interface{}) {
+	l.add(severityError, field, format, args...)
+}
+
+func (l *linter) warnf(field, format string, args ...interface{}) {
+	l.add(severityWarning, field, format, args...)
+}
+
+func (l *linter) add(severity, field, format string, args ...interface{}) {
+	l.findings = append(l.findings, finding{
+		Severity: severity,
+		Field:    field,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// lintFunction checks the syntax of a function, and returns true if it is
+// valid.
+func (l *linter) lintFunction(field string, value interface{}, required bool) bool {
+	src, ok := value.(string)
+	switch {
+	case value == nil && !required:
+		return false
+	case !ok || strings.TrimSpace(src) == "":
+		l.errorf(field, "Function must be a non-empty string")
+		return false
+	}
+	if err := checkSyntax(src); err != nil {
+		l.errorf(field, "Syntax error: %s", err)
+		return false
+	}
+	return true
+}
+
+func (l *linter) lintMap(field, src string) {
+	if emitDocRE.MatchString(src) {
+		l.warnf(field, "Emitting the whole document duplicates it in the index; emit null and query with include_docs=true instead")
+	}
+	if m := nonDeterministicRE.FindString(src); m != "" {
+		l.warnf(field, "Map functions must be deterministic; avoid %s", strings.TrimRight(m, "( \t\n"))
+	}
+}
+
+func (l *linter) lintReduce(field string, value interface{}) {
+	src, _ := value.(string)
+	if name := strings.TrimSpace(src); strings.HasPrefix(name, "_") {
+		if !builtinReducers[name] {
+			l.errorf(field, "Unknown built-in reducer '%s'", name)
+		}
+		return
+	}
+	if !l.lintFunction(field, value, true) {
+		return
+	}
+	if builtin, ok := duplicateReducers[functionBody(src)]; ok {
+		l.warnf(field, "Custom reduce function duplicates the built-in %s reducer, which is much faster", builtin)
+		return
+	}
+	if !strings.Contains(src, "rereduce") {
+		l.warnf(field, "Reduce function does not handle rereduce")
+	}
+}
+
+// lintIndexes validates the Mango index definitions in the views of a design
+// document with the language `query`.
+func (l *linter) lintIndexes(value interface{}) {
+	views, _ := value.(map[string]interface{})
+	for _, name := range sortedKeys(views) {
+		field := "views/" + name
+		view, _ := views[name].(map[string]interface{})
+		index, _ := view["map"].(map[string]interface{})
+		if index == nil {
+			l.errorf(field+"/map", "Mango index definition must be an object")
+			continue
+		}
+		fields, _ := index["fields"].(map[string]interface{})
+		if len(fields) == 0 {
+			l.errorf(field+"/map/fields", "Mango index must include at least one field")
+		}
+		for _, f := range sortedKeys(fields) {
+			if dir := fields[f]; dir != "asc" && dir != "desc" {
+				l.errorf(field+"/map/fields", "Invalid sort direction '%v' for field '%s'. Supported options: `asc`, `desc`", dir, f)
+			}
+		}
+		if pfs, ok := index["partial_filter_selector"]; ok {
+			if _, ok := pfs.(map[string]interface{}); !ok {
+				l.errorf(field+"/map/partial_filter_selector", "Partial filter selector must be an object")
+			}
+		}
+		if reduce, ok := view["reduce"]; ok && reduce != "_count" {
+			l.errorf(field+"/reduce", "Mango indexes must use the _count reducer")
+		}
+	}
+}
+
+// functionBody returns the body of a JavaScript function, with whitespace and
+// semicolons removed.
+func functionBody(src string) string {
+	start, end := strings.Index(src, "{"), strings.LastIndex(src, "}")
+	if start < 0 || end < start {
+		return ""
+	}
+	return whitespaceRE.ReplaceAllString(src[start+1:end], "")
+}
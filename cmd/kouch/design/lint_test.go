@@ -0,0 +1,147 @@
+package design
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/lint"
+)
+
+func TestLintDesignDoc(t *testing.T) {
+	type tst struct {
+		doc      string
+		expected []finding
+	}
+	tests := testy.NewTable()
+	tests.Add("clean", tst{
+		doc: `{"views":{"a":{"map":"function(doc) { emit(doc.a, null); }","reduce":"_count"}},"validate_doc_update":"function(newDoc) {}"}`,
+	})
+	tests.Add("emit whole doc", tst{
+		doc: `{"views":{"a":{"map":"function(doc) { emit(doc._id, doc); }"}}}`,
+		expected: []finding{
+			{Severity: "warning", Field: "views/a/map", Message: "Emitting the whole document duplicates it in the index; emit null and query with include_docs=true instead"},
+		},
+	})
+	tests.Add("non-deterministic", tst{
+		doc: `{"views":{"a":{"map":"function(doc) { if (doc.expires > Date.now()) emit(doc._id); }"}}}`,
+		expected: []finding{
+			{Severity: "warning", Field: "views/a/map", Message: "Map functions must be deterministic; avoid Date.now"},
+		},
+	})
+	tests.Add("syntax errors", tst{
+		doc: `{"views":{"a":{"map":"function(doc) { emit(doc._id) "}},"filters":{"f":"function(doc) { return doc.a == 'x; }"},"updates":{"u":42}}`,
+		expected: []finding{
+			{Severity: "error", Field: "views/a/map", Message: "Syntax error: line 1: Unexpected end of input"},
+			{Severity: "error", Field: "filters/f", Message: "Syntax error: line 1: Unexpected token ILLEGAL"},
+			{Severity: "error", Field: "updates/u", Message: "Function must be a non-empty string"},
+		},
+	})
+	tests.Add("missing map", tst{
+		doc: `{"views":{"a":{"reduce":"_sum"},"b":"function(doc) {}"}}`,
+		expected: []finding{
+			{Severity: "error", Field: "views/a/map", Message: "Function must be a non-empty string"},
+			{Severity: "error", Field: "views/b", Message: "View definition must be an object"},
+		},
+	})
+	tests.Add("reducers", tst{
+		doc: `{"views":{
+			"a":{"map":"function(doc) {}","reduce":"_summ"},
+			"b":{"map":"function(doc) {}","reduce":"function(keys, values, rereduce) {\n  return sum(values);\n}"},
+			"c":{"map":"function(doc) {}","reduce":"function(keys, values) { return values.length; }"},
+			"d":{"map":"function(doc) {}","reduce":"function(keys, values) { return values[0]; }"},
+			"e":{"map":"function(doc) {}","reduce":"function(keys, values, rereduce) { return rereduce ? values[0] : 1; }"}
+		}}`,
+		expected: []finding{
+			{Severity: "error", Field: "views/a/reduce", Message: "Unknown built-in reducer '_summ'"},
+			{Severity: "warning", Field: "views/b/reduce", Message: "Custom reduce function duplicates the built-in _sum reducer, which is much faster"},
+			{Severity: "warning", Field: "views/c/reduce", Message: "Custom reduce function duplicates the built-in _count reducer, which is much faster"},
+			{Severity: "warning", Field: "views/d/reduce", Message: "Reduce function does not handle rereduce"},
+		},
+	})
+	tests.Add("mango indexes", tst{
+		doc: `{"language":"query","views":{
+			"a":{"map":{"fields":{"name":"asc"},"partial_filter_selector":{}},"reduce":"_count"},
+			"b":{"map":{"fields":{}}},
+			"c":{"map":{"fields":{"name":"up"},"partial_filter_selector":[]},"reduce":"_sum"},
+			"d":{"map":"function(doc) {}"}
+		}}`,
+		expected: []finding{
+			{Severity: "error", Field: "views/b/map/fields", Message: "Mango index must include at least one field"},
+			{Severity: "error", Field: "views/c/map/fields", Message: "Invalid sort direction 'up' for field 'name'. Supported options: `asc`, `desc`"},
+			{Severity: "error", Field: "views/c/map/partial_filter_selector", Message: "Partial filter selector must be an object"},
+			{Severity: "error", Field: "views/c/reduce", Message: "Mango indexes must use the _count reducer"},
+			{Severity: "error", Field: "views/d/map", Message: "Mango index definition must be an object"},
+		},
+	})
+
+	tests.Run(t, func(t *testing.T, tt tst) {
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+			t.Fatal(err)
+		}
+		if d := diff.Interface(tt.expected, lintDesignDoc(doc)); d != nil {
+			t.Error(d)
+		}
+	})
+}
+
+func TestLintDesignCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("clean dir", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{
+			"views/a/map.js": "function(doc) { emit(doc.a, null); }",
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args: []string{dir},
+			},
+		}
+	})
+	tests.Add("warnings", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{
+			"views/a/map.js": "function(doc) { emit(doc.a, doc); }",
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir},
+				Stdout: `{"field":"views/a/map","message":"Emitting the whole document duplicates it in the index; emit null and query with include_docs=true instead","severity":"warning"}`,
+			},
+		}
+	})
+	tests.Add("strict", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{
+			"views/a/map.js": "function(doc) { emit(doc.a, doc); }",
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, "--strict"},
+				Stdout: `{"field":"views/a/map","message":"Emitting the whole document duplicates it in the index; emit null and query with include_docs=true instead","severity":"warning"}`,
+				Err:    "Found 0 error(s) and 1 warning(s)",
+				Status: chttp.ExitUnknownFailure,
+			},
+		}
+	})
+	tests.Add("server", func(t *testing.T) interface{} {
+		s, requests := designServer(t, tests, map[string]response{
+			"GET /foo/_design/app": {http.StatusOK, `{"_id":"_design/app","validate_doc_update":"function(doc) {"}`},
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo/app"},
+				Stdout: `{"field":"validate_doc_update","message":"Syntax error: line 1: Unexpected end of input","severity":"error"}`,
+				Err:    "Found 1 error(s) and 0 warning(s)",
+				Status: chttp.ExitUnknownFailure,
+			},
+			requests: requests,
+			expected: []string{"GET /foo/_design/app"},
+		}
+	})
+
+	runDesignTests(t, tests, []string{"lint", "design"})
+}
@@ -0,0 +1,117 @@
+package design
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const flagFormat = "format"
+
+func init() {
+	registry.Register([]string{"pull"}, pullDesignCmd)
+}
+
+func pullDesignCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "design <target> <dir>",
+		Aliases: []string{"ddoc"},
+		Short:   "Pulls a design document into a local source tree.",
+		Long: "Explodes a design document into a couchapp-style source directory, which may be pushed back to the server with 'kouch push design'.\n\n" +
+			"Functions, such as views/by-name/map.js and validate_doc_update.js, are written to .js files, attachments to the _attachments directory, " +
+			"and any other field to a JSON or YAML file named after the field.\n\n" +
+			"Existing files are only overwritten with --" + kouch.FlagClobber + ", and the directory is only created with --" + kouch.FlagCreateDirs + ". " +
+			"Files which no longer correspond to a field are not removed.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDocument),
+		Args:              cobra.ExactArgs(2),
+		PersistentPreRunE: util.PositionalArgs,
+		RunE:              pullDesignDocCmd,
+	}
+	f := cmd.Flags()
+	f.StringP(kouch.FlagRev, kouch.FlagShortRev, "", "Pull the specified revision.")
+	f.String(flagFormat, formatJSON, "The format of fields other than functions. Supported options: `json`, `yaml`.")
+	return cmd
+}
+
+func pullDesignDocCmd(cmd *cobra.Command, args []string) error {
+	ctx := kouch.SetTarget(kouch.GetContext(cmd), args[0])
+	o, w, err := pullDesignOpts(ctx, cmd.Flags(), args[1])
+	if err != nil {
+		return err
+	}
+	return pullDesign(ctx, o, w)
+}
+
+func pullDesignOpts(ctx context.Context, flags *pflag.FlagSet, dir string) (*kouch.Options, *layoutWriter, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDocument, flags)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := validateTarget(o.Target); err != nil {
+		return nil, nil, err
+	}
+	if !strings.HasPrefix(o.Document, "_design/") {
+		o.Document = "_design/" + o.Document
+	}
+	o.Query().Set("attachments", "true")
+	w := &layoutWriter{dir: dir}
+	if w.format, err = flags.GetString(flagFormat); err != nil {
+		return nil, nil, err
+	}
+	if w.format != formatJSON && w.format != formatYAML {
+		return nil, nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid format '%s'. Supported options: `json`, `yaml`", w.format)
+	}
+	if w.clobber, err = flags.GetBool(kouch.FlagClobber); err != nil {
+		return nil, nil, err
+	}
+	createDirs, err := flags.GetBool(kouch.FlagCreateDirs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkDir(dir, createDirs); err != nil {
+		return nil, nil, err
+	}
+	return o, w, nil
+}
+
+// checkDir ensures that dir exists, creating it if createDirs is set.
+func checkDir(dir string, createDirs bool) error {
+	info, err := os.Stat(dir)
+	switch {
+	case err == nil && !info.IsDir():
+		return errors.NewExitError(chttp.ExitWriteError, "%s is not a directory", dir)
+	case err == nil:
+		return nil
+	case !os.IsNotExist(err):
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	case !createDirs:
+		return errors.NewExitError(chttp.ExitWriteError, "Directory %s does not exist. Use --%s to create it", dir, kouch.FlagCreateDirs)
+	}
+	return errors.WrapExitError(chttp.ExitWriteError, os.MkdirAll(dir, os.ModePerm))
+}
+
+func pullDesign(ctx context.Context, o *kouch.Options, w *layoutWriter) error {
+	doc, err := fetchDesignDoc(ctx, o)
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		return errors.NewExitError(chttp.ExitNotRetrieved, "Design document '%s' not found", o.Document)
+	}
+	if err := w.write(doc); err != nil {
+		return err
+	}
+	return writeResult(ctx, map[string]interface{}{
+		"id":    doc["_id"],
+		"rev":   doc["_rev"],
+		"files": w.files,
+	})
+}
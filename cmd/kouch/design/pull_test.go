@@ -0,0 +1,181 @@
+package design
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+type pullTest struct {
+	test.CmdTest
+	dir  string
+	tree map[string]string
+}
+
+// readTree reads all files below dir, keyed by slash-separated path.
+func readTree(t *testing.T, dir string) map[string]string {
+	tree := make(map[string]string)
+	err := filepath.Walk(dir, func(filename string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, filename)
+		tree[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+const pulledDoc = `{"_id":"_design/app","_rev":"1-abc","language":"javascript","options":{"partitioned":false},` +
+	`"validate_doc_update":"function() {}","filters":{"f":"function(doc) { return true; }"},` +
+	`"views":{"by-name":{"map":"function(doc) { emit(doc.name); }","reduce":"_count"}},` +
+	`"_attachments":{"index.html":{"content_type":"text/html","data":"PGh0bWw+PC9odG1sPg=="}}}`
+
+func TestPullDesignCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("missing dir", func(t *testing.T) interface{} {
+		dir := filepath.Join(writeTree(t, tests, nil), "missing")
+		return pullTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{"http://localhost/foo/_design/app", dir},
+				Err:    "Directory " + dir + " does not exist. Use --create-dirs to create it",
+				Status: chttp.ExitWriteError,
+			},
+		}
+	})
+	tests.Add("invalid format", func(t *testing.T) interface{} {
+		return pullTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{"http://localhost/foo/_design/app", ".", "--format", "xml"},
+				Err:    "Invalid format 'xml'. Supported options: `json`, `yaml`",
+				Status: chttp.ExitFailedToInitialize,
+			},
+		}
+	})
+	tests.Add("not found", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, nil)
+		s, _ := designServer(t, tests, nil)
+		return pullTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo/app", dir, "--create-dirs"},
+				Err:    "Design document '_design/app' not found",
+				Status: chttp.ExitNotRetrieved,
+			},
+		}
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		dir := filepath.Join(writeTree(t, tests, nil), "new")
+		s, _ := designServer(t, tests, map[string]response{
+			"GET /foo/_design/app": {http.StatusOK, pulledDoc},
+		})
+		return pullTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo/_design/app", dir, "--create-dirs"},
+				Stdout: `{"files":["_id","_attachments/index.html","filters/f.js","language.json","options.json","validate_doc_update.js","views/by-name/map.js","views/by-name/reduce.js"],"id":"_design/app","rev":"1-abc"}`,
+			},
+			dir: dir,
+			tree: map[string]string{
+				"_id":                     "_design/app\n",
+				"_attachments/index.html": "<html></html>",
+				"filters/f.js":            "function(doc) { return true; }",
+				"language.json":           "\"javascript\"\n",
+				"options.json":            "{\n  \"partitioned\": false\n}\n",
+				"validate_doc_update.js":  "function() {}",
+				"views/by-name/map.js":    "function(doc) { emit(doc.name); }",
+				"views/by-name/reduce.js": "_count",
+			},
+		}
+	})
+	tests.Add("yaml", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, nil)
+		s, _ := designServer(t, tests, map[string]response{
+			"GET /foo/_design/app": {http.StatusOK, `{"_id":"_design/app","_rev":"1-abc","options":{"partitioned":false}}`},
+		})
+		return pullTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo/_design/app", dir, "--format", "yaml"},
+				Stdout: `{"files":["_id","options.yaml"],"id":"_design/app","rev":"1-abc"}`,
+			},
+			dir: dir,
+			tree: map[string]string{
+				"_id":          "_design/app\n",
+				"options.yaml": "partitioned: false\n",
+			},
+		}
+	})
+	tests.Add("no force", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{"_id": "old"})
+		s, _ := designServer(t, tests, map[string]response{
+			"GET /foo/_design/app": {http.StatusOK, pulledDoc},
+		})
+		return pullTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo/_design/app", dir},
+				Err:    "open " + filepath.Join(dir, "_id") + ": file exists",
+				Status: chttp.ExitWriteError,
+			},
+		}
+	})
+	tests.Add("force", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{"_id": "old"})
+		s, _ := designServer(t, tests, map[string]response{
+			"GET /foo/_design/app": {http.StatusOK, `{"_id":"_design/app","_rev":"1-abc"}`},
+		})
+		return pullTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo/_design/app", dir, "--force"},
+				Stdout: `{"files":["_id"],"id":"_design/app","rev":"1-abc"}`,
+			},
+			dir:  dir,
+			tree: map[string]string{"_id": "_design/app\n"},
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt pullTest) {
+		test.ValidateCmdTest([]string{"pull", "design"})(t, tt.CmdTest)
+		if tt.tree == nil {
+			return
+		}
+		if d := diff.Interface(tt.tree, readTree(t, tt.dir)); d != nil {
+			t.Errorf("Unexpected files:\n%s", d)
+		}
+	})
+}
+
+func TestPullPushRoundTrip(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "kouch-design")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp) // nolint: errcheck
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(pulledDoc), &doc); err != nil {
+		t.Fatal(err)
+	}
+	w := &layoutWriter{dir: tmp, format: formatYAML}
+	if err := w.write(doc); err != nil {
+		t.Fatal(err)
+	}
+	result, err := readDesignDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if designDocChanged(result, doc) {
+		t.Errorf("Unexpected change after round trip:\n%s", diff.AsJSON(doc, result))
+	}
+}
@@ -0,0 +1,165 @@
+package design
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/go-kivik/kouch/kouchio"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"push"}, pushDesignCmd)
+}
+
+func pushDesignCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "design <dir> [db]",
+		Aliases: []string{"ddoc"},
+		Short:   "Pushes a design document from a local source tree.",
+		Long: "Assembles a design document from a couchapp-style source directory, and stores it on the server if it differs from the current version.\n\n" +
+			"Each sub-directory becomes an object, and each file a field named after the file, without its extension. " +
+			"Files with a .json or .yaml extension are parsed; any other file, such as views/by-name/map.js or validate_doc_update.js, is stored as a string. " +
+			"Files in the _attachments directory are stored as attachments, and hidden files are ignored.\n\n" +
+			"The document ID is read from the file _id, if present, or else taken from the name of the directory.\n\n" +
+			"The database may be given as a name, or as a full URL, such as http://localhost:5984/db.",
+		Args:              cobra.RangeArgs(1, 2),
+		PersistentPreRunE: util.PositionalArgs,
+		RunE:              pushDesignDocCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided as the second argument.")
+	f.BoolP(kouch.FlagAutoRev, kouch.FlagShortAutoRev, false, "Update the design document if it already exists, using the current rev. Use with caution!")
+	f.BoolP(kouch.FlagYes, kouch.FlagShortYes, false, "Do not prompt for confirmation when using a protected context.")
+	return cmd
+}
+
+type pusher struct {
+	o       *kouch.Options
+	doc     map[string]interface{}
+	autoRev bool
+}
+
+func pushDesignDocCmd(cmd *cobra.Command, args []string) error {
+	doc, err := readDesignDir(args[0])
+	if err != nil {
+		return err
+	}
+	ctx := kouch.GetContext(cmd)
+	p, err := pushDesignOpts(ctx, cmd.Flags(), doc, args[1:])
+	if err != nil {
+		return err
+	}
+	return p.push(ctx, cmd.Flags())
+}
+
+func pushDesignOpts(ctx context.Context, flags *pflag.FlagSet, doc map[string]interface{}, args []string) (*pusher, error) {
+	o, err := designDocOpts(ctx, flags, doc["_id"].(string), args)
+	if err != nil {
+		return nil, err
+	}
+	p := &pusher{
+		o:   o,
+		doc: doc,
+	}
+	if p.autoRev, err = flags.GetBool(kouch.FlagAutoRev); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// designDocOpts returns options targeting the design document id, in the
+// database given by the optional argument in args.
+func designDocOpts(ctx context.Context, flags *pflag.FlagSet, id string, args []string) (*kouch.Options, error) {
+	if len(args) > 0 && args[0] != "" {
+		id = args[0] + "/" + id
+	}
+	ctx = kouch.SetTarget(ctx, id)
+	o := kouch.NewOptions()
+	var err error
+	if o.Target, err = kouch.NewTarget(ctx, kouch.TargetDocument, flags); err != nil {
+		return nil, err
+	}
+	if err := validateTarget(o.Target); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (p *pusher) push(ctx context.Context, flags *pflag.FlagSet) error {
+	current, err := fetchDesignDoc(ctx, p.o)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		if !designDocChanged(p.doc, current) {
+			_, _ = fmt.Fprintf(os.Stderr, "Design document '%s' is unchanged\n", p.o.Document)
+			return writeResult(ctx, map[string]interface{}{
+				"ok":  true,
+				"id":  current["_id"],
+				"rev": current["_rev"],
+			})
+		}
+		if p.autoRev {
+			p.doc["_rev"] = current["_rev"]
+		}
+	}
+	if err := util.ConfirmMutation(p.o, flags, fmt.Sprintf("You are about to update the design document '%s' in the database '%s'.", p.o.Document, p.o.Database), p.o.Database); err != nil {
+		return err
+	}
+	p.o.Body = chttp.EncodeBody(p.doc)
+	return util.ChttpDo(ctx, http.MethodPut, util.DocPath(p.o), p.o)
+}
+
+// fetchDesignDoc fetches the current version of the target design document,
+// or returns nil if it does not exist.
+func fetchDesignDoc(ctx context.Context, o *kouch.Options) (map[string]interface{}, error) {
+	c, err := o.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.DoReq(ctx, http.MethodGet, util.DocPath(o), o.Options)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close() // nolint: errcheck
+	if err = chttp.ResponseError(res); err != nil {
+		if kivik.StatusCode(err) == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitWeirdReply, err)
+	}
+	return doc, nil
+}
+
+func writeResult(ctx context.Context, result interface{}) error {
+	out := kouch.Output(ctx)
+	if err := json.NewEncoder(out).Encode(result); err != nil {
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	return kouchio.CloseWriter(out)
+}
+
+func validateTarget(t *kouch.Target) error {
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package design
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/pull"
+	_ "github.com/go-kivik/kouch/cmd/kouch/push"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+type designTest struct {
+	test.CmdTest
+	requests *[]string
+	expected []string
+}
+
+type response struct {
+	status int
+	body   string
+}
+
+// designServer returns a server which responds to requests, in the format
+// "METHOD /path", from responses, and records each request, with its body, in
+// the returned slice. Unknown requests receive a 404.
+func designServer(t *testing.T, tests *testy.Table, responses map[string]response) (*httptest.Server, *[]string) {
+	var requests []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.URL.RequestURI()+" "+string(body)))
+		w.Header().Set("Content-Type", "application/json")
+		res, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			res = response{status: http.StatusNotFound, body: `{"error":"not_found","reason":"missing"}`}
+		}
+		w.WriteHeader(res.status)
+		_, _ = w.Write([]byte(res.body))
+	}))
+	tests.Cleanup(s.Close)
+	return s, &requests
+}
+
+func runDesignTests(t *testing.T, tests *testy.Table, args []string) {
+	tests.Run(t, func(t *testing.T, tt designTest) {
+		test.ValidateCmdTest(args)(t, tt.CmdTest)
+		if tt.requests != nil {
+			if d := diff.Interface(tt.expected, *tt.requests); d != nil {
+				t.Errorf("Unexpected requests:\n%s", d)
+			}
+		}
+	})
+}
+
+// writeTree creates the files in tree, keyed by slash-separated path, below
+// a new temporary directory, and returns the directory's path.
+func writeTree(t *testing.T, tests *testy.Table, tree map[string]string) string {
+	tmp, err := ioutil.TempDir("", "kouch-design")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests.Cleanup(func() { _ = os.RemoveAll(tmp) })
+	dir := filepath.Join(tmp, "app")
+	if err := os.Mkdir(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range tree {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+var appTree = map[string]string{
+	"views/by-name/map.js":    "function(doc) { emit(doc.name); }",
+	"views/by-name/reduce.js": "_count",
+	"validate_doc_update.js":  "function() {}",
+	"options.json":            `{"partitioned":false}`,
+	"_attachments/index.html": "<html></html>",
+	".git/HEAD":               "ref: refs/heads/master",
+}
+
+const appDoc = `{"_attachments":{"index.html":{"content_type":"text/html; charset=utf-8","data":"PGh0bWw+PC9odG1sPg=="}},"_id":"_design/app","options":{"partitioned":false},"validate_doc_update":"function() {}","views":{"by-name":{"map":"function(doc) { emit(doc.name); }","reduce":"_count"}}}`
+
+const appStub = `{"_id":"_design/app","_rev":"1-abc","_attachments":{"index.html":{"content_type":"text/html; charset=utf-8","digest":"md5-yDMBQlsq0dSWRzpf89nsyg==","length":13,"revpos":1,"stub":true}},"options":{"partitioned":false},"validate_doc_update":"function() {}","views":{"by-name":{"map":"function(doc) { emit(doc.name); }","reduce":"_count"}}}`
+
+func TestPushDesignCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("missing dir", designTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"/nonexistent/app", "http://localhost/foo"},
+			Err:    "stat /nonexistent/app: no such file or directory",
+			Status: chttp.ExitReadError,
+		},
+	})
+	tests.Add("no database", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, appTree)
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, "--root", "http://localhost/"},
+				Err:    "No database name provided",
+				Status: chttp.ExitFailedToInitialize,
+			},
+		}
+	})
+	tests.Add("create", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, appTree)
+		s, requests := designServer(t, tests, map[string]response{
+			"PUT /foo/_design/app": {http.StatusCreated, `{"ok":true,"id":"_design/app","rev":"1-abc"}`},
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, s.URL + "/foo"},
+				Stdout: `{"id":"_design/app","ok":true,"rev":"1-abc"}`,
+			},
+			requests: requests,
+			expected: []string{
+				"GET /foo/_design/app",
+				"PUT /foo/_design/app " + appDoc,
+			},
+		}
+	})
+	tests.Add("unchanged", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, appTree)
+		s, requests := designServer(t, tests, map[string]response{
+			"GET /foo/_design/app": {http.StatusOK, appStub},
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, s.URL + "/foo"},
+				Stdout: `{"id":"_design/app","ok":true,"rev":"1-abc"}`,
+				Stderr: "Design document '_design/app' is unchanged\n",
+			},
+			requests: requests,
+			expected: []string{"GET /foo/_design/app"},
+		}
+	})
+	tests.Add("changed, auto-rev", func(t *testing.T) interface{} {
+		tree := map[string]string{
+			"_id":          "_design/custom",
+			"language":     "javascript",
+			"filters/f.js": "function(doc) { return true; }",
+		}
+		dir := writeTree(t, tests, tree)
+		s, requests := designServer(t, tests, map[string]response{
+			"GET /foo/_design/custom": {http.StatusOK, `{"_id":"_design/custom","_rev":"1-abc","language":"javascript"}`},
+			"PUT /foo/_design/custom": {http.StatusCreated, `{"ok":true,"id":"_design/custom","rev":"2-def"}`},
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, s.URL + "/foo", "--auto-rev"},
+				Stdout: `{"id":"_design/custom","ok":true,"rev":"2-def"}`,
+			},
+			requests: requests,
+			expected: []string{
+				"GET /foo/_design/custom",
+				`PUT /foo/_design/custom {"_id":"_design/custom","_rev":"1-abc","filters":{"f":"function(doc) { return true; }"},"language":"javascript"}`,
+			},
+		}
+	})
+	tests.Add("changed, conflict", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{"language": "javascript"})
+		s, requests := designServer(t, tests, map[string]response{
+			"GET /foo/_design/app": {http.StatusOK, `{"_id":"_design/app","_rev":"1-abc"}`},
+			"PUT /foo/_design/app": {http.StatusConflict, `{"error":"conflict","reason":"Document update conflict."}`},
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, "--database", "foo", "--root", s.URL},
+				Err:    "Conflict: Document update conflict.",
+				Status: chttp.ExitNotRetrieved,
+			},
+			requests: requests,
+			expected: []string{
+				"GET /foo/_design/app",
+				`PUT /foo/_design/app {"_id":"_design/app","language":"javascript"}`,
+			},
+		}
+	})
+	tests.Add("conflicting files", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{
+			"options.json": "{}",
+			"options.yaml": "{}",
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, "http://localhost/foo"},
+				Err:    "Conflicting entries for 'options' in " + dir,
+				Status: chttp.ExitReadError,
+			},
+		}
+	})
+
+	runDesignTests(t, tests, []string{"push", "design"})
+}
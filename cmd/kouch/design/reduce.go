@@ -0,0 +1,113 @@
+package design
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// reducer reduces a group of view rows to a single value.
+type reducer func(rows []viewRow) (json.RawMessage, error)
+
+// builtinReducer returns the native implementation of a built-in reducer, or
+// nil if name is not recognized.
+func builtinReducer(name string) reducer {
+	switch name {
+	case "_count":
+		return reduceCount
+	case "_sum":
+		return reduceSum
+	case "_stats":
+		return reduceStats
+	case "_approx_count_distinct":
+		return reduceCountDistinct
+	}
+	return nil
+}
+
+func reduceCount(rows []viewRow) (json.RawMessage, error) {
+	return json.Marshal(len(rows))
+}
+
+// reduceSum sums numeric values, or, element-wise, arrays of numbers.
+func reduceSum(rows []viewRow) (json.RawMessage, error) {
+	var total float64
+	var totals []float64
+	for _, row := range rows {
+		var value interface{}
+		if err := json.Unmarshal(row.Value, &value); err != nil {
+			return nil, err
+		}
+		switch v := value.(type) {
+		case float64:
+			if totals != nil {
+				return nil, errSumValue
+			}
+			total += v
+			continue
+		case []interface{}:
+			if total != 0 {
+				return nil, errSumValue
+			}
+			if totals == nil {
+				totals = []float64{}
+			}
+			for i, e := range v {
+				n, ok := e.(float64)
+				if !ok {
+					return nil, errSumValue
+				}
+				if i < len(totals) {
+					totals[i] += n
+				} else {
+					totals = append(totals, n)
+				}
+			}
+			continue
+		}
+		return nil, errSumValue
+	}
+	if totals != nil {
+		return json.Marshal(totals)
+	}
+	return json.Marshal(total)
+}
+
+var errSumValue = fmt.Errorf("_sum requires map values to be numbers or arrays of numbers")
+
+type stats struct {
+	Sum    float64 `json:"sum"`
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	SumSqr float64 `json:"sumsqr"`
+}
+
+func reduceStats(rows []viewRow) (json.RawMessage, error) {
+	var s stats
+	for i, row := range rows {
+		var value float64
+		if err := json.Unmarshal(row.Value, &value); err != nil {
+			return nil, fmt.Errorf("_stats requires map values to be numbers")
+		}
+		if i == 0 || value < s.Min {
+			s.Min = value
+		}
+		if i == 0 || value > s.Max {
+			s.Max = value
+		}
+		s.Sum += value
+		s.SumSqr += value * value
+		s.Count++
+	}
+	return json.Marshal(s)
+}
+
+// reduceCountDistinct counts the distinct keys. Unlike CouchDB, which uses
+// HyperLogLog, the count is exact.
+func reduceCountDistinct(rows []viewRow) (json.RawMessage, error) {
+	seen := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		seen[string(row.Key)] = true
+	}
+	return json.Marshal(len(seen))
+}
@@ -0,0 +1,69 @@
+package design
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/flimzy/testy"
+)
+
+func TestBuiltinReducer(t *testing.T) {
+	type tst struct {
+		name     string
+		keys     []string
+		values   []string
+		expected string
+		err      string
+	}
+	tests := testy.NewTable()
+	tests.Add("count", tst{
+		name:     "_count",
+		values:   []string{`1`, `"a"`, `null`},
+		expected: `3`,
+	})
+	tests.Add("sum", tst{
+		name:     "_sum",
+		values:   []string{`1`, `2.5`, `-1`},
+		expected: `2.5`,
+	})
+	tests.Add("sum arrays", tst{
+		name:     "_sum",
+		values:   []string{`[1,2]`, `[3]`, `[1,1,1]`},
+		expected: `[5,3,1]`,
+	})
+	tests.Add("sum invalid", tst{
+		name:   "_sum",
+		values: []string{`1`, `"a"`},
+		err:    "_sum requires map values to be numbers or arrays of numbers",
+	})
+	tests.Add("stats", tst{
+		name:     "_stats",
+		values:   []string{`2`, `-1`, `4`},
+		expected: `{"sum":5,"count":3,"min":-1,"max":4,"sumsqr":21}`,
+	})
+	tests.Add("stats invalid", tst{
+		name:   "_stats",
+		values: []string{`[1]`},
+		err:    "_stats requires map values to be numbers",
+	})
+	tests.Add("approx count distinct", tst{
+		name:     "_approx_count_distinct",
+		keys:     []string{`"a"`, `"b"`, `"a"`, `["a"]`},
+		expected: `3`,
+	})
+
+	tests.Run(t, func(t *testing.T, tt tst) {
+		var rows []viewRow
+		for _, key := range tt.keys {
+			rows = append(rows, viewRow{Key: json.RawMessage(key), Value: json.RawMessage(`null`)})
+		}
+		for _, value := range tt.values {
+			rows = append(rows, viewRow{Key: json.RawMessage(`null`), Value: json.RawMessage(value)})
+		}
+		result, err := builtinReducer(tt.name)(rows)
+		testy.Error(t, tt.err, err)
+		if string(result) != tt.expected {
+			t.Errorf("Unexpected result: %s", result)
+		}
+	})
+}
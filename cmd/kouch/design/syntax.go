@@ -0,0 +1,40 @@
+package design
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+)
+
+// checkSyntax parses src, which must be a single JavaScript function
+// expression. The parser supports ECMAScript 5, so newer syntax, such as arrow
+// functions, is reported as an error. Regular expressions are not checked for
+// constructs which Go's regexp package does not support.
+func checkSyntax(src string) error {
+	// The source is parenthesized, as an anonymous function is not a valid
+	// statement.
+	program, err := parser.ParseFile(nil, "", "("+src+"\n)", parser.IgnoreRegExpErrors)
+	if err != nil {
+		if errs, ok := err.(parser.ErrorList); ok && len(errs) > 0 {
+			err = errs[0]
+		}
+		if e, ok := err.(*parser.Error); ok {
+			// Errors after the last line of src are in the closing parenthesis.
+			if lines := strings.Count(src, "\n") + 1; e.Position.Line > lines {
+				return fmt.Errorf("line %d: Unexpected end of input", lines)
+			}
+			return fmt.Errorf("line %d: %s", e.Position.Line, e.Message)
+		}
+		return err
+	}
+	if len(program.Body) == 1 {
+		if stmt, ok := program.Body[0].(*ast.ExpressionStatement); ok {
+			if _, ok := stmt.Expression.(*ast.FunctionLiteral); ok {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("expected a function expression")
+}
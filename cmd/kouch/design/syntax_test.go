@@ -0,0 +1,34 @@
+package design
+
+import (
+	"testing"
+
+	"github.com/flimzy/testy"
+)
+
+func TestCheckSyntax(t *testing.T) {
+	type tst struct {
+		src string
+		err string
+	}
+	tests := testy.NewTable()
+	tests.Add("valid", tst{src: "function(doc) { if (doc.a) { emit([doc.a, 1], null); } }"})
+	tests.Add("not a function", tst{src: "emit(doc._id)", err: "expected a function expression"})
+	tests.Add("trailing statement", tst{src: "function(doc) {}; emit(doc._id)", err: "line 1: Unexpected token ;"})
+	tests.Add("unclosed brace", tst{src: "function(doc) {\n  emit(doc._id);\n", err: "line 3: Unexpected end of input"})
+	tests.Add("mismatched bracket", tst{src: "function(doc) {\n  emit([doc._id);\n}", err: "line 2: Unexpected token )"})
+	tests.Add("brackets in strings and comments", tst{src: "function(doc) {\n  // }\n  /* ) */ emit('{', \"[\");\n}"})
+	tests.Add("missing argument", tst{src: "function(doc) { emit(doc._id,, ) }", err: "line 1: Unexpected token ,"})
+	tests.Add("unterminated string", tst{src: "function(doc) {\n\n  emit('foo);\n}", err: "line 3: Unexpected token ILLEGAL"})
+	tests.Add("unterminated comment", tst{src: "function(doc) { /* emit(doc._id); }", err: "line 1: Unexpected end of input"})
+	tests.Add("regex", tst{src: "function(doc) { if (/^[)}]+\\//.test(doc.a)) emit(doc.a / 2); }"})
+	tests.Add("regex after return", tst{src: "function(doc) { return /[)}]/.test(doc.a); }"})
+	tests.Add("unsupported regex", tst{src: "function(doc) { return /a(?!b)/.test(doc.a); }"})
+	tests.Add("unterminated regex", tst{src: "function(doc) { var re = /abc;\n}", err: "line 1: Invalid regular expression: missing /"})
+	tests.Add("arrow function", tst{src: "(doc) => emit(doc._id)", err: "line 1: Unexpected token >"})
+
+	tests.Run(t, func(t *testing.T, tt tst) {
+		err := checkSyntax(tt.src)
+		testy.Error(t, tt.err, err)
+	})
+}
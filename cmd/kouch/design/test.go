@@ -0,0 +1,497 @@
+package design
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	kio "github.com/go-kivik/kouch/io"
+	"github.com/robertkrimen/otto"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagDocs   = "docs"
+	flagExpect = "expect"
+)
+
+func init() {
+	registry.Register([]string{"test"}, testDesignCmd)
+}
+
+func testDesignCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "design <dir|ddoc-file>",
+		Aliases: []string{"ddoc"},
+		Short:   "Runs a view of a design document over fixture documents.",
+		Long: "Runs the map function of a view over fixture documents, using an embedded JavaScript interpreter, and outputs the rows in the same format as a view query, without a server.\n\n" +
+			"The design document is read from a couchapp-style source directory, as used by 'kouch push design', or from a JSON or YAML file. " +
+			"The fixtures are read from the file given by --" + flagDocs + ", as a JSON array, JSON Lines, or, with a .yaml extension, YAML documents. " +
+			"Design documents and deleted documents among the fixtures are not indexed.\n\n" +
+			"If the design document has a validate_doc_update function, each fixture is first passed to it, as a new document created by an anonymous user. " +
+			"Rejected fixtures are written to stderr, and are not indexed. " +
+			"As with CouchDB, a fixture for which the map function throws an error is also skipped, and the error written to stderr.\n\n" +
+			"The rows are reduced, unless --" + kouch.FlagReduce + "=false is given. " +
+			"The built-in reducers _sum, _count, _stats and _approx_count_distinct are supported; _approx_count_distinct returns an exact count. " +
+			"Custom reduce functions are called for each half of a group with more than one row, and the results combined with rereduce.\n\n" +
+			"With --" + flagExpect + ", the rows are compared to the rows in the given file, in the same formats as the fixtures, or as a view response. " +
+			"The exit status is non-zero if they differ.",
+		Args:              cobra.ExactArgs(1),
+		PersistentPreRunE: util.PositionalArgs,
+		RunE:              testDesignDocCmd,
+	}
+	f := cmd.Flags()
+	f.String(flagDocs, "", "File containing the fixture documents.")
+	f.String(kouch.FlagView, "", "The view to run. May be omitted if the design document has only one view.")
+	f.Bool(kouch.FlagReduce, true, "Use the reduce function of the view.")
+	f.Bool(kouch.FlagGroup, false, "Group the reduced rows by key.")
+	f.Int(kouch.FlagGroupLevel, 0, "Group the reduced rows by the first N elements of array keys.")
+	f.Bool(kouch.FlagDescending, false, "Return the rows in descending order.")
+	f.String(flagExpect, "", "File containing the expected rows.")
+	return cmd
+}
+
+// viewRow is a row of view output.
+type viewRow struct {
+	ID    string          `json:"id,omitempty"`
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value"`
+	// key is the decoded key, for collation.
+	key interface{}
+}
+
+type designTester struct {
+	doc        map[string]interface{}
+	docs       string
+	expect     string
+	view       string
+	reduce     bool
+	group      bool
+	groupLevel int
+	descending bool
+	rejects    io.Writer
+	js         *jsRuntime
+}
+
+func testDesignDocCmd(cmd *cobra.Command, args []string) error {
+	t, err := testDesignOpts(cmd.Flags(), args[0])
+	if err != nil {
+		return err
+	}
+	return t.run(kouch.GetContext(cmd))
+}
+
+func testDesignOpts(flags *pflag.FlagSet, src string) (*designTester, error) {
+	t := &designTester{rejects: os.Stderr}
+	var err error
+	if t.docs, err = flags.GetString(flagDocs); err != nil {
+		return nil, err
+	}
+	if t.docs == "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s is required", flagDocs)
+	}
+	if t.expect, err = flags.GetString(flagExpect); err != nil {
+		return nil, err
+	}
+	if t.view, err = flags.GetString(kouch.FlagView); err != nil {
+		return nil, err
+	}
+	if t.reduce, err = flags.GetBool(kouch.FlagReduce); err != nil {
+		return nil, err
+	}
+	if t.group, err = flags.GetBool(kouch.FlagGroup); err != nil {
+		return nil, err
+	}
+	if t.groupLevel, err = flags.GetInt(kouch.FlagGroupLevel); err != nil {
+		return nil, err
+	}
+	if t.groupLevel < 0 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must not be negative", kouch.FlagGroupLevel)
+	}
+	if t.descending, err = flags.GetBool(kouch.FlagDescending); err != nil {
+		return nil, err
+	}
+	if t.doc, err = readDesignSource(src); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// readDesignSource reads a design document from a couchapp-style source
+// directory, or from a JSON or YAML file.
+func readDesignSource(src string) (map[string]interface{}, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	if info.IsDir() {
+		return readDesignDir(src)
+	}
+	_, value, err := readEntry(src, info)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.NewExitError(chttp.ExitReadError, "%s does not contain a JSON or YAML design document", src)
+	}
+	return doc, nil
+}
+
+func (t *designTester) run(ctx context.Context) error {
+	mapSrc, reduceSrc, err := t.selectView()
+	if err != nil {
+		return err
+	}
+	if t.js, err = newJSRuntime(os.Stderr); err != nil {
+		return err
+	}
+	mapFn, err := t.compile("views/"+t.view+"/map", mapSrc)
+	if err != nil {
+		return err
+	}
+	var reduce reducer
+	if reduceSrc != "" && t.reduce {
+		if reduce, err = t.reducer(reduceSrc); err != nil {
+			return err
+		}
+	}
+	if (t.group || t.groupLevel > 0) && reduce == nil {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "--%s and --%s are only valid when reducing", kouch.FlagGroup, kouch.FlagGroupLevel)
+	}
+	rows, err := t.mapDocs(mapFn)
+	if err != nil {
+		return err
+	}
+	result := map[string]interface{}{}
+	if reduce != nil {
+		if rows, err = t.reduceRows(rows, reduce); err != nil {
+			return err
+		}
+	} else {
+		result["total_rows"] = len(rows)
+		result["offset"] = 0
+	}
+	if t.descending {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	result["rows"] = rows
+	if err := writeResult(ctx, result); err != nil {
+		return err
+	}
+	if t.expect == "" {
+		return nil
+	}
+	return t.checkRows(rows)
+}
+
+// selectView returns the map and reduce functions of the selected view, and
+// sets t.view if it was not given.
+func (t *designTester) selectView() (mapSrc, reduceSrc string, err error) {
+	if lang, _ := t.doc["language"].(string); lang != "" && lang != "javascript" {
+		return "", "", errors.NewExitError(chttp.ExitFailedToInitialize, "Design documents with the language '%s' cannot be tested", lang)
+	}
+	views, _ := t.doc["views"].(map[string]interface{})
+	if t.view == "" {
+		if len(views) != 1 {
+			return "", "", errors.NewExitError(chttp.ExitFailedToInitialize, "Design document has %d views; select one with --%s", len(views), kouch.FlagView)
+		}
+		t.view = sortedKeys(views)[0]
+	}
+	view, ok := views[t.view].(map[string]interface{})
+	if !ok {
+		return "", "", errors.NewExitError(chttp.ExitFailedToInitialize, "View '%s' not found", t.view)
+	}
+	mapSrc, _ = view["map"].(string)
+	if mapSrc == "" {
+		return "", "", errors.NewExitError(chttp.ExitFailedToInitialize, "View '%s' has no map function", t.view)
+	}
+	reduceSrc, _ = view["reduce"].(string)
+	return mapSrc, reduceSrc, nil
+}
+
+func (t *designTester) compile(field, src string) (otto.Value, error) {
+	if err := checkSyntax(src); err != nil {
+		return otto.Value{}, errors.NewExitError(chttp.ExitFailedToInitialize, "%s: Syntax error: %s", field, err)
+	}
+	fn, err := t.js.compile(src)
+	if err != nil {
+		return otto.Value{}, errors.NewExitError(chttp.ExitFailedToInitialize, "%s: %s", field, err)
+	}
+	return fn, nil
+}
+
+func (t *designTester) reducer(src string) (reducer, error) {
+	if name := strings.TrimSpace(src); strings.HasPrefix(name, "_") {
+		reduce := builtinReducer(name)
+		if reduce == nil {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Unknown built-in reducer '%s'", name)
+		}
+		return reduce, nil
+	}
+	fn, err := t.compile("views/"+t.view+"/reduce", src)
+	if err != nil {
+		return nil, err
+	}
+	return t.customReducer(fn), nil
+}
+
+// fixtureMeta is the metadata of a fixture document.
+type fixtureMeta struct {
+	ID      string `json:"_id"`
+	Deleted bool   `json:"_deleted"`
+}
+
+// mapDocs runs mapFn over the fixtures, which are first validated, and
+// returns the emitted rows in collation order.
+func (t *designTester) mapDocs(mapFn otto.Value) ([]viewRow, error) {
+	var validate otto.Value
+	if src, _ := t.doc["validate_doc_update"].(string); src != "" {
+		var err error
+		if validate, err = t.compile("validate_doc_update", src); err != nil {
+			return nil, err
+		}
+	}
+	docs, err := readDocs(t.docs)
+	if err != nil {
+		return nil, err
+	}
+	rows := []viewRow{}
+	rejects := json.NewEncoder(t.rejects)
+	for i, doc := range docs {
+		var meta fixtureMeta
+		if err := json.Unmarshal(doc, &meta); err != nil {
+			return nil, errors.NewExitError(chttp.ExitReadError, "Fixture %d: %s", i+1, err)
+		}
+		if meta.ID == "" {
+			return nil, errors.NewExitError(chttp.ExitReadError, "Fixture %d has no _id", i+1)
+		}
+		if meta.Deleted || strings.HasPrefix(meta.ID, "_design/") {
+			continue
+		}
+		if validate.IsDefined() {
+			reject, err := t.validateDoc(validate, meta.ID, doc)
+			if err != nil {
+				return nil, err
+			}
+			if reject != nil {
+				if err := rejects.Encode(reject); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		result, err := t.js.exec(mapFn, doc)
+		if err != nil {
+			reason, _ := json.Marshal(err.Error())
+			if err := rejects.Encode(rejection{ID: meta.ID, Error: "map_error", Reason: reason}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for _, kv := range result.Emitted {
+			key, err := decodeKey(kv[0])
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, viewRow{ID: meta.ID, Key: kv[0], Value: kv[1], key: key})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if c := collateKeys(rows[i].key, rows[j].key); c != 0 {
+			return c < 0
+		}
+		return collator.CompareString(rows[i].ID, rows[j].ID) < 0
+	})
+	return rows, nil
+}
+
+// rejection reports a fixture rejected by validate_doc_update, or for which
+// the map function failed.
+type rejection struct {
+	ID     string          `json:"id"`
+	Error  string          `json:"error"`
+	Reason json.RawMessage `json:"reason"`
+}
+
+// validateDoc calls validate_doc_update for doc, as a new document created by
+// an anonymous user. A rejection is returned if the function throws
+// {forbidden: ...} or {unauthorized: ...}.
+func (t *designTester) validateDoc(validate otto.Value, id string, doc json.RawMessage) (*rejection, error) {
+	userCtx := map[string]interface{}{"db": "", "name": nil, "roles": []string{}}
+	result, err := t.js.exec(validate, doc, nil, userCtx, map[string]interface{}{})
+	if err != nil {
+		return nil, errors.NewExitError(chttp.ExitUnknownFailure, "validate_doc_update: document '%s': %s", id, err)
+	}
+	if len(result.Thrown) == 0 {
+		return nil, nil
+	}
+	var thrown map[string]json.RawMessage
+	_ = json.Unmarshal(result.Thrown, &thrown)
+	for _, name := range []string{"forbidden", "unauthorized"} {
+		if reason, ok := thrown[name]; ok {
+			return &rejection{ID: id, Error: name, Reason: reason}, nil
+		}
+	}
+	return nil, errors.NewExitError(chttp.ExitUnknownFailure, "validate_doc_update: document '%s': threw %s", id, result.Thrown)
+}
+
+// customReducer returns a reducer which calls fn. Groups of more than one row
+// are reduced in two halves, which are then combined with rereduce.
+func (t *designTester) customReducer(fn otto.Value) reducer {
+	call := func(args ...interface{}) (json.RawMessage, error) {
+		result, err := t.js.exec(fn, args...)
+		if err != nil {
+			return nil, errors.NewExitError(chttp.ExitUnknownFailure, "views/%s/reduce: %s", t.view, err)
+		}
+		if len(result.Thrown) > 0 {
+			return nil, errors.NewExitError(chttp.ExitUnknownFailure, "views/%s/reduce: threw %s", t.view, result.Thrown)
+		}
+		return result.Result, nil
+	}
+	reduce := func(rows []viewRow) (json.RawMessage, error) {
+		keys := make([][2]interface{}, len(rows))
+		values := make([]json.RawMessage, len(rows))
+		for i, row := range rows {
+			keys[i] = [2]interface{}{row.Key, row.ID}
+			values[i] = row.Value
+		}
+		return call(keys, values, false)
+	}
+	return func(rows []viewRow) (json.RawMessage, error) {
+		if len(rows) < 2 {
+			return reduce(rows)
+		}
+		mid := len(rows) / 2
+		first, err := reduce(rows[:mid])
+		if err != nil {
+			return nil, err
+		}
+		second, err := reduce(rows[mid:])
+		if err != nil {
+			return nil, err
+		}
+		return call(nil, []json.RawMessage{first, second}, true)
+	}
+}
+
+// reduceRows groups rows, which must be in collation order, according to the
+// grouping options, and reduces each group.
+func (t *designTester) reduceRows(rows []viewRow, reduce reducer) ([]viewRow, error) {
+	reduced := []viewRow{}
+	if len(rows) == 0 {
+		return reduced, nil
+	}
+	keys := make([]viewRow, len(rows))
+	for i, row := range rows {
+		keys[i] = t.groupKey(row)
+	}
+	for start := 0; start < len(rows); {
+		end := start + 1
+		for end < len(rows) && collateKeys(keys[start].key, keys[end].key) == 0 {
+			end++
+		}
+		value, err := reduce(rows[start:end])
+		if err != nil {
+			return nil, err
+		}
+		reduced = append(reduced, viewRow{Key: keys[start].Key, Value: value})
+		start = end
+	}
+	return reduced, nil
+}
+
+// groupKey returns a row whose key is the group key of row.
+func (t *designTester) groupKey(row viewRow) viewRow {
+	switch {
+	case t.groupLevel > 0:
+		array, ok := row.key.([]interface{})
+		if !ok || len(array) <= t.groupLevel {
+			return row
+		}
+		var elems []json.RawMessage
+		_ = json.Unmarshal(row.Key, &elems)
+		key, _ := json.Marshal(elems[:t.groupLevel])
+		return viewRow{Key: key, key: array[:t.groupLevel]}
+	case t.group:
+		return row
+	}
+	return viewRow{Key: json.RawMessage("null")}
+}
+
+// readDocs reads all documents from filename, as YAML if it has a .yaml or
+// .yml extension, or else as JSON.
+func readDocs(filename string) ([]json.RawMessage, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	defer f.Close() // nolint: errcheck
+	format := kio.InputFormatJSON
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		format = kio.InputFormatYAML
+	}
+	r, err := kio.NewDocReader(f, format)
+	if err != nil {
+		return nil, err
+	}
+	docs := []json.RawMessage{}
+	for {
+		doc, err := r.Next()
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// checkRows compares rows to the expected rows.
+func (t *designTester) checkRows(rows []viewRow) error {
+	expected, err := readDocs(t.expect)
+	if err != nil {
+		return err
+	}
+	if len(expected) == 1 {
+		var response struct {
+			Rows []json.RawMessage `json:"rows"`
+		}
+		if json.Unmarshal(expected[0], &response) == nil && response.Rows != nil {
+			expected = response.Rows
+		}
+	}
+	if len(expected) != len(rows) {
+		return errors.NewExitError(chttp.ExitUnknownFailure, "Expected %d rows, got %d", len(expected), len(rows))
+	}
+	for i, row := range rows {
+		actual, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		var want, got interface{}
+		if err := json.Unmarshal(expected[i], &want); err != nil {
+			return errors.WrapExitError(chttp.ExitReadError, err)
+		}
+		_ = json.Unmarshal(actual, &got)
+		if !reflect.DeepEqual(want, got) {
+			return errors.NewExitError(chttp.ExitUnknownFailure, "Row %d differs: expected %s, got %s", i+1, expected[i], actual)
+		}
+	}
+	return nil
+}
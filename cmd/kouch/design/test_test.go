@@ -0,0 +1,175 @@
+package design
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/tester"
+)
+
+const fixtures = `{"_id":"a","type":"b","n":3}
+{"_id":"b","type":"a","n":1}
+{"_id":"c","type":"a","n":2,"bad":true}
+{"_id":"d","type":"Z","n":5}
+{"_id":"e","type":"a","n":10}
+{"_id":"f","n":4}
+{"_id":"_design/x","type":"a"}
+{"_id":"g","type":"a","_deleted":true}
+`
+
+var byTypeTree = map[string]string{
+	"views/by-type/map.js":   "function(doc) { if (doc.type) { emit([doc.type, doc.n], doc.n); } }",
+	"validate_doc_update.js": "function(newDoc) { if (newDoc.bad) { throw({forbidden: 'bad doc'}); } }",
+}
+
+func TestTestDesignCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("no docs", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, byTypeTree)
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir},
+				Err:    "--docs is required",
+				Status: chttp.ExitFailedToInitialize,
+			},
+		}
+	})
+	tests.Add("map", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, byTypeTree)
+		fx := writeTree(t, tests, map[string]string{"docs.jsonl": fixtures})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, "--docs", filepath.Join(fx, "docs.jsonl")},
+				Stdout: `{"offset":0,"rows":[{"id":"b","key":["a",1],"value":1},{"id":"e","key":["a",10],"value":10},{"id":"a","key":["b",3],"value":3},{"id":"d","key":["Z",5],"value":5}],"total_rows":4}`,
+				Stderr: `{"id":"c","error":"forbidden","reason":"bad doc"}` + "\n",
+			},
+		}
+	})
+	tests.Add("descending", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{
+			"views/by-n/map.js": "function(doc) { emit(doc.n, null); }",
+		})
+		fx := writeTree(t, tests, map[string]string{"docs.json": `[{"_id":"x","n":2},{"_id":"y","n":1},{"_id":"z","n":"a"}]`})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, "--docs", filepath.Join(fx, "docs.json"), "--descending"},
+				Stdout: `{"offset":0,"rows":[{"id":"z","key":"a","value":null},{"id":"x","key":2,"value":null},{"id":"y","key":1,"value":null}],"total_rows":3}`,
+			},
+		}
+	})
+	tests.Add("map error", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{
+			"views/by-x/map.js": "function(doc) { emit(doc.x.y, null); }",
+		})
+		fx := writeTree(t, tests, map[string]string{"docs.jsonl": `{"_id":"a","x":{"y":1}}` + "\n" + `{"_id":"b"}`})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, "--docs", filepath.Join(fx, "docs.jsonl")},
+				Stdout: `{"offset":0,"rows":[{"id":"a","key":1,"value":null}],"total_rows":1}`,
+				Stderr: `{"id":"b","error":"map_error","reason":"TypeError: Cannot access member 'y' of undefined"}` + "\n",
+			},
+		}
+	})
+	tests.Add("syntax error", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{
+			"views/by-x/map.js": "function(doc) { emit(doc.x,, ) }",
+		})
+		fx := writeTree(t, tests, map[string]string{"docs.jsonl": `{"_id":"a"}`})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, "--docs", filepath.Join(fx, "docs.jsonl")},
+				Err:    "views/by-x/map: Syntax error: line 1: Unexpected token ,",
+				Status: chttp.ExitFailedToInitialize,
+			},
+		}
+	})
+	tests.Add("sum by group level", func(t *testing.T) interface{} {
+		tree := map[string]string{"views/by-type/reduce": "_sum"}
+		for k, v := range byTypeTree {
+			tree[k] = v
+		}
+		dir := writeTree(t, tests, tree)
+		fx := writeTree(t, tests, map[string]string{"docs.jsonl": fixtures})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, "--docs", filepath.Join(fx, "docs.jsonl"), "--group-level", "1"},
+				Stdout: `{"rows":[{"key":["a"],"value":11},{"key":["b"],"value":3},{"key":["Z"],"value":5}]}`,
+				Stderr: `{"id":"c","error":"forbidden","reason":"bad doc"}` + "\n",
+			},
+		}
+	})
+	tests.Add("custom reduce", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{
+			"views/by-type/map.js":    "function(doc) { emit(doc.type, doc.n); }",
+			"views/by-type/reduce.js": "function(keys, values, rereduce) { if (rereduce) { log({rereduce: values}); } return sum(values); }",
+		})
+		fx := writeTree(t, tests, map[string]string{"docs.jsonl": fixtures})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, "--docs", filepath.Join(fx, "docs.jsonl"), "--group"},
+				Stdout: `{"rows":[{"key":null,"value":4},{"key":"a","value":13},{"key":"b","value":3},{"key":"Z","value":5}]}`,
+				Stderr: `{"rereduce":[1,12]}` + "\n",
+			},
+		}
+	})
+	tests.Add("group without reduce", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, byTypeTree)
+		fx := writeTree(t, tests, map[string]string{"docs.jsonl": fixtures})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, "--docs", filepath.Join(fx, "docs.jsonl"), "--group"},
+				Err:    "--group and --group-level are only valid when reducing",
+				Status: chttp.ExitFailedToInitialize,
+			},
+		}
+	})
+	tests.Add("select view", func(t *testing.T) interface{} {
+		fx := writeTree(t, tests, map[string]string{
+			"ddoc.yaml":  "views:\n  a:\n    map: 'function(doc) { emit(1); }'\n  b:\n    map: 'function(doc) { emit(2); }'\n    reduce: _count\n",
+			"docs.yaml":  "- _id: p\n- _id: q\n",
+			"expect.txt": `{"key":null,"value":2}`,
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{filepath.Join(fx, "ddoc.yaml"), "--docs", filepath.Join(fx, "docs.yaml"), "--view", "b", "--expect", filepath.Join(fx, "expect.txt")},
+				Stdout: `{"rows":[{"key":null,"value":2}]}`,
+			},
+		}
+	})
+	tests.Add("ambiguous view", func(t *testing.T) interface{} {
+		fx := writeTree(t, tests, map[string]string{
+			"ddoc.json": `{"views":{"a":{"map":"function(doc) {}"},"b":{"map":"function(doc) {}"}}}`,
+			"docs.json": `[]`,
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{filepath.Join(fx, "ddoc.json"), "--docs", filepath.Join(fx, "docs.json")},
+				Err:    "Design document has 2 views; select one with --view",
+				Status: chttp.ExitFailedToInitialize,
+			},
+		}
+	})
+	tests.Add("unexpected rows", func(t *testing.T) interface{} {
+		dir := writeTree(t, tests, map[string]string{
+			"views/by-n/map.js": "function(doc) { emit(doc.n, null); }",
+		})
+		fx := writeTree(t, tests, map[string]string{
+			"docs.json":   `[{"_id":"x","n":2},{"_id":"y","n":1}]`,
+			"expect.json": `{"total_rows":2,"offset":0,"rows":[{"id":"y","key":1,"value":null},{"id":"x","key":3,"value":null}]}`,
+		})
+		return designTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{dir, "--docs", filepath.Join(fx, "docs.json"), "--expect", filepath.Join(fx, "expect.json")},
+				Stdout: `{"offset":0,"rows":[{"id":"y","key":1,"value":null},{"id":"x","key":2,"value":null}],"total_rows":2}`,
+				Err:    `Row 2 differs: expected {"id":"x","key":3,"value":null}, got {"id":"x","key":2,"value":null}`,
+				Status: chttp.ExitUnknownFailure,
+			},
+		}
+	})
+
+	runDesignTests(t, tests, []string{"test", "design"})
+}
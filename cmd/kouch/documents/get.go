@@ -27,6 +27,7 @@ func getDocCmd() *cobra.Command {
 	f := cmd.Flags()
 	f.String(kouch.FlagDocument, "", "The document ID. May be provided with the target in the format {id}.")
 	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}/{id}.")
+	f.String(kouch.FlagPartition, "", "The partition of a partitioned database. The document ID must then be in the format {partition}:{id}.")
 	f.StringP(kouch.FlagRev, kouch.FlagShortRev, "", "Retrieves document of specified revision.")
 	f.String(kouch.FlagIfNoneMatch, "", "Optionally fetch the document, only if the current rev does not match the one provided")
 
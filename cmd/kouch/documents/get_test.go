@@ -71,6 +71,23 @@ func TestGetDocumentOpts(t *testing.T) {
 			Options: &chttp.Options{},
 		},
 	})
+	tests.Add("partitioned doc id", test.OptionsTest{
+		Args: []string{"http://foo.com/foo/bar:123", "--" + kouch.FlagPartition, "bar"},
+		Expected: &kouch.Options{
+			Target: &kouch.Target{
+				Root:      "http://foo.com",
+				Database:  "foo",
+				Partition: "bar",
+				Document:  "bar:123",
+			},
+			Options: &chttp.Options{},
+		},
+	})
+	tests.Add("doc id missing partition prefix", test.OptionsTest{
+		Args:   []string{"http://foo.com/foo/123", "--" + kouch.FlagPartition, "bar"},
+		Err:    "Document ID '123' must begin with the partition prefix 'bar:'",
+		Status: chttp.ExitFailedToInitialize,
+	})
 	tests.Add("if-none-match", test.OptionsTest{
 		Args: []string{"--" + kouch.FlagIfNoneMatch, "foo", "foo.com/bar/baz"},
 		Expected: &kouch.Options{
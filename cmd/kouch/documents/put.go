@@ -2,6 +2,7 @@ package documents
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/go-kivik/kouch"
@@ -27,9 +28,11 @@ func putDocCmd() *cobra.Command {
 	f := cmd.Flags()
 	f.String(kouch.FlagDocument, "", "The document ID. May be provided with the target in the format {id}.")
 	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}/{id}.")
+	f.String(kouch.FlagPartition, "", "The partition of a partitioned database. The document ID must then be in the format {partition}:{id}.")
 	f.StringP(kouch.FlagRev, kouch.FlagShortRev, "", "Retrieves document of specified revision.")
 	f.Bool(kouch.FlagFullCommit, false, "Overrides server’s commit policy.")
 	f.BoolP(kouch.FlagAutoRev, kouch.FlagShortAutoRev, false, "Fetch the current rev before update. Use with caution!")
+	f.BoolP(kouch.FlagYes, kouch.FlagShortYes, false, "Do not prompt for confirmation when using a protected context.")
 
 	f.Bool(kouch.FlagBatch, false, "Store document in batch mode.")
 	f.Bool(kouch.FlagNewEdits, true, "When disabled, prevents insertion of conflicting documents.")
@@ -66,5 +69,8 @@ func putDocumentCmd(cmd *cobra.Command, args []string) error {
 	if err := validateTarget(o.Target); err != nil {
 		return err
 	}
+	if err := util.ConfirmMutation(o, cmd.Flags(), fmt.Sprintf("You are about to update the document '%s' in the database '%s'.", o.Document, o.Database), o.Database); err != nil {
+		return err
+	}
 	return util.ChttpDo(ctx, http.MethodPut, util.DocPath(o), o)
 }
@@ -0,0 +1,287 @@
+package dump
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/go-kivik/kouch/kouchio"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagBatchSize      = "batch-size"
+	flagDesignDocs     = "design"
+	flagLocalDocs      = "local"
+	flagDeleted        = "deleted"
+	flagAttachmentMode = "attachment-mode"
+	flagAttachmentDir  = "attachment-dir"
+)
+
+// Attachment modes
+const (
+	attModeStub    = "stub"
+	attModeInline  = "inline"
+	attModeSidecar = "sidecar"
+)
+
+const defaultBatchSize = 1000
+
+func init() {
+	registry.Register(nil, dumpCmd)
+}
+
+func dumpCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dump [target]",
+		Short: "Dumps a database as JSON Lines.",
+		Long: "Dumps the contents of a database, writing one document per line to the output.\n\n" +
+			"The database is read in batches, so arbitrarily large databases may be dumped without being held in memory.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: dumpDatabaseCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}.")
+	f.Int(flagBatchSize, defaultBatchSize, "Number of documents to fetch per request.")
+	f.Bool(flagDesignDocs, true, "Include design documents.")
+	f.Bool(flagLocalDocs, false, "Include local (non-replicating) documents. Requires CouchDB 2.2 or newer.")
+	f.Bool(flagDeleted, false, "Include deleted documents. The changes feed is used, rather than _all_docs.")
+	f.Bool(kouch.FlagConflicts, false, "Include conflicts information with each document.")
+	f.String(flagAttachmentMode, attModeStub, "How to handle attachments. Supported options: `stub` (attachment stubs only), `inline` (Base64-encoded in the document), `sidecar` (written to separate files, see --"+flagAttachmentDir+").")
+	f.String(flagAttachmentDir, "", "Directory where attachments are written, when --"+flagAttachmentMode+"=sidecar.")
+	return cmd
+}
+
+type dumper struct {
+	o         *kouch.Options
+	client    *chttp.Client
+	out       io.Writer
+	batchSize int
+	design    bool
+	local     bool
+	deleted   bool
+	conflicts bool
+	attMode   string
+	attDir    string
+}
+
+func dumpDatabaseCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	d, err := dumpOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if err := validateTarget(d.o.Target); err != nil {
+		return err
+	}
+	d.client, err = d.o.NewClient()
+	if err != nil {
+		return err
+	}
+	out := kouchio.Underlying(kouch.Output(ctx))
+	err = d.dump(ctx, out)
+	if e := kouchio.CloseWriter(out); e != nil && err == nil {
+		err = errors.WrapExitError(chttp.ExitWriteError, e)
+	}
+	return err
+}
+
+func dumpOpts(ctx context.Context, flags *pflag.FlagSet) (*dumper, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	d := &dumper{o: o}
+	if d.batchSize, err = flags.GetInt(flagBatchSize); err != nil {
+		return nil, err
+	}
+	if d.batchSize < 1 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must be a positive integer", flagBatchSize)
+	}
+	if d.design, err = flags.GetBool(flagDesignDocs); err != nil {
+		return nil, err
+	}
+	if d.local, err = flags.GetBool(flagLocalDocs); err != nil {
+		return nil, err
+	}
+	if d.deleted, err = flags.GetBool(flagDeleted); err != nil {
+		return nil, err
+	}
+	if d.conflicts, err = flags.GetBool(kouch.FlagConflicts); err != nil {
+		return nil, err
+	}
+	if d.attMode, err = flags.GetString(flagAttachmentMode); err != nil {
+		return nil, err
+	}
+	if d.attDir, err = flags.GetString(flagAttachmentDir); err != nil {
+		return nil, err
+	}
+	switch d.attMode {
+	case attModeStub, attModeInline:
+		if d.attDir != "" {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s requires --%s=%s", flagAttachmentDir, flagAttachmentMode, attModeSidecar)
+		}
+	case attModeSidecar:
+		if d.attDir == "" {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s=%s requires --%s", flagAttachmentMode, attModeSidecar, flagAttachmentDir)
+		}
+	default:
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid value for --%s. Supported options: `%s`, `%s`, `%s`", flagAttachmentMode, attModeStub, attModeInline, attModeSidecar)
+	}
+	return d, nil
+}
+
+func validateTarget(t *kouch.Target) error {
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
+
+func (d *dumper) dump(ctx context.Context, out io.Writer) error {
+	var err error
+	if d.deleted {
+		err = d.dumpChanges(ctx, out)
+	} else {
+		err = d.dumpAllDocs(ctx, out, "_all_docs")
+	}
+	if err != nil || !d.local {
+		return err
+	}
+	return d.dumpAllDocs(ctx, out, "_local_docs")
+}
+
+// query returns the query parameters common to every request.
+func (d *dumper) query() url.Values {
+	q := url.Values{
+		"include_docs": []string{"true"},
+		"limit":        []string{strconv.Itoa(d.batchSize)},
+	}
+	if d.conflicts {
+		q.Set("conflicts", "true")
+	}
+	if d.attMode != attModeStub {
+		q.Set("attachments", "true")
+	}
+	return q
+}
+
+// page fetches a single page of results from path, calling fn for each element
+// of field in the response. The number of elements, and the remaining fields
+// of the response, are returned.
+func (d *dumper) page(ctx context.Context, path string, query url.Values, field string, fn func(json.RawMessage) error) (int, map[string]json.RawMessage, error) {
+	res, err := d.client.DoReq(ctx, http.MethodGet, path, &chttp.Options{Query: query})
+	if err != nil {
+		return 0, nil, err
+	}
+	if err = chttp.ResponseError(res); err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close() // nolint: errcheck
+	var count int
+	other, err := util.StreamArray(res.Body, field, func(raw json.RawMessage) error {
+		count++
+		return fn(raw)
+	})
+	return count, other, err
+}
+
+type row struct {
+	ID  string          `json:"id"`
+	Key json.RawMessage `json:"key"`
+	Doc json.RawMessage `json:"doc"`
+}
+
+func (d *dumper) dumpAllDocs(ctx context.Context, out io.Writer, endpoint string) error {
+	path := util.EndpointPath(d.o, endpoint)
+	query := d.query()
+	for {
+		var lastKey json.RawMessage
+		count, _, err := d.page(ctx, path, query, "rows", func(raw json.RawMessage) error {
+			var r row
+			if err := json.Unmarshal(raw, &r); err != nil {
+				return errors.WrapExitError(chttp.ExitWeirdReply, err)
+			}
+			lastKey = r.Key
+			if !d.include(r.ID) {
+				return nil
+			}
+			return d.writeDoc(out, r.ID, r.Doc)
+		})
+		if err != nil {
+			return err
+		}
+		if count < d.batchSize {
+			return nil
+		}
+		query.Set("startkey", string(lastKey))
+		query.Set("skip", "1")
+	}
+}
+
+type change struct {
+	ID  string          `json:"id"`
+	Doc json.RawMessage `json:"doc"`
+}
+
+func (d *dumper) dumpChanges(ctx context.Context, out io.Writer) error {
+	path := util.EndpointPath(d.o, "_changes")
+	query := d.query()
+	for {
+		count, other, err := d.page(ctx, path, query, "results", func(raw json.RawMessage) error {
+			var c change
+			if err := json.Unmarshal(raw, &c); err != nil {
+				return errors.WrapExitError(chttp.ExitWeirdReply, err)
+			}
+			if !d.include(c.ID) {
+				return nil
+			}
+			return d.writeDoc(out, c.ID, c.Doc)
+		})
+		if err != nil {
+			return err
+		}
+		if count < d.batchSize {
+			return nil
+		}
+		query.Set("since", strings.Trim(string(other["last_seq"]), `"`))
+	}
+}
+
+// include returns true if the document should be included in the dump.
+func (d *dumper) include(docID string) bool {
+	return d.design || !strings.HasPrefix(docID, "_design/")
+}
+
+func (d *dumper) writeDoc(out io.Writer, docID string, doc json.RawMessage) error {
+	if len(doc) == 0 || string(doc) == "null" {
+		return nil
+	}
+	if d.attMode == attModeSidecar {
+		var err error
+		if doc, err = d.writeSidecars(docID, doc); err != nil {
+			return err
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := json.Compact(buf, doc); err != nil {
+		return errors.WrapExitError(chttp.ExitWeirdReply, err)
+	}
+	buf.WriteByte('\n')
+	_, err := out.Write(buf.Bytes())
+	return errors.WrapExitError(chttp.ExitWriteError, err)
+}
@@ -0,0 +1,165 @@
+package dump
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func dumpServer(t *testing.T, responses map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path + "?" + r.URL.RawQuery
+		body, ok := responses[key]
+		if !ok {
+			t.Errorf("Unexpected request: %s", key)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+func TestDumpCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid attachment mode", test.CmdTest{
+		Args:   []string{"http://localhost/foo", "--" + flagAttachmentMode, "oink"},
+		Err:    "Invalid value for --attachment-mode. Supported options: `stub`, `inline`, `sidecar`",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("sidecar without dir", test.CmdTest{
+		Args:   []string{"http://localhost/foo", "--" + flagAttachmentMode, attModeSidecar},
+		Err:    "--attachment-mode=sidecar requires --attachment-dir",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("paged all docs", func(t *testing.T) interface{} {
+		s := dumpServer(t, map[string]string{
+			"/foo/_all_docs?include_docs=true&limit=2": `{"total_rows":3,"offset":0,"rows":[
+				{"id":"_design/x","key":"_design/x","value":{"rev":"1-a"},"doc":{"_id":"_design/x","_rev":"1-a"}},
+				{"id":"a","key":"a","value":{"rev":"1-b"},"doc":{"_id":"a", "_rev":"1-b"}}
+			]}`,
+			"/foo/_all_docs?include_docs=true&limit=2&skip=1&startkey=%22a%22": `{"total_rows":3,"offset":2,"rows":[
+				{"id":"b","key":"b","value":{"rev":"1-c"},"doc":{"_id":"b","_rev":"1-c"}}
+			]}`,
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo", "--" + flagBatchSize, "2"},
+			Stdout: `{"_id":"_design/x","_rev":"1-a"}
+{"_id":"a","_rev":"1-b"}
+{"_id":"b","_rev":"1-c"}
+`,
+		}
+	})
+	tests.Add("no design docs, with local docs", func(t *testing.T) interface{} {
+		s := dumpServer(t, map[string]string{
+			"/foo/_all_docs?include_docs=true&limit=1000": `{"total_rows":2,"offset":0,"rows":[
+				{"id":"_design/x","key":"_design/x","value":{"rev":"1-a"},"doc":{"_id":"_design/x","_rev":"1-a"}},
+				{"id":"a","key":"a","value":{"rev":"1-b"},"doc":{"_id":"a","_rev":"1-b"}}
+			]}`,
+			"/foo/_local_docs?include_docs=true&limit=1000": `{"total_rows":null,"offset":null,"rows":[
+				{"id":"_local/x","key":"_local/x","value":{"rev":"0-1"},"doc":{"_id":"_local/x","_rev":"0-1"}}
+			]}`,
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo", "--" + flagDesignDocs + "=false", "--" + flagLocalDocs},
+			Stdout: `{"_id":"a","_rev":"1-b"}
+{"_id":"_local/x","_rev":"0-1"}
+`,
+		}
+	})
+	tests.Add("deleted docs from changes feed", func(t *testing.T) interface{} {
+		s := dumpServer(t, map[string]string{
+			"/foo/_changes?conflicts=true&include_docs=true&limit=2": `{"results":[
+				{"seq":"1-x","id":"a","changes":[{"rev":"1-b"}],"doc":{"_id":"a","_rev":"1-b","_conflicts":["1-c"]}},
+				{"seq":"2-x","id":"b","changes":[{"rev":"2-c"}],"deleted":true,"doc":{"_id":"b","_rev":"2-c","_deleted":true}}
+			],"last_seq":"2-x","pending":0}`,
+			"/foo/_changes?conflicts=true&include_docs=true&limit=2&since=2-x": `{"results":[],"last_seq":"2-x","pending":0}`,
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo", "--" + flagDeleted, "--conflicts", "--" + flagBatchSize, "2"},
+			Stdout: `{"_id":"a","_rev":"1-b","_conflicts":["1-c"]}
+{"_id":"b","_rev":"2-c","_deleted":true}
+`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"dump"}))
+}
+
+func TestDumpEmptyToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kouch-dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+	s := dumpServer(t, map[string]string{
+		"/foo/_all_docs?include_docs=true&limit=1000": `{"total_rows":0,"offset":0,"rows":[]}`,
+	})
+	defer s.Close()
+	test.ValidateCmdTest([]string{"dump"})(t, test.CmdTest{
+		Args: []string{s.URL + "/foo", "-o", filepath.Join(dir, "foo.jsonl")},
+	})
+}
+
+func TestSidecarSegment(t *testing.T) {
+	tests := map[string]string{
+		"foo.txt": "foo.txt",
+		"a/b":     "a%2Fb",
+		".":       "%2E",
+		"..":      "%2E%2E",
+		"...":     "%2E%2E%2E",
+		"../x":    "..%2Fx",
+	}
+	for in, expected := range tests {
+		if got := sidecarSegment(in); got != expected {
+			t.Errorf("sidecarSegment(%q) = %q, expected %q", in, got, expected)
+		}
+	}
+}
+
+func TestDumpSidecar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kouch-dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+	s := dumpServer(t, map[string]string{
+		"/foo/_all_docs?attachments=true&include_docs=true&limit=1000": `{"total_rows":1,"offset":0,"rows":[
+			{"id":"a/b","key":"a/b","value":{"rev":"1-b"},"doc":{"_id":"a/b","_rev":"1-b","_attachments":{"foo.txt":{"content_type":"text/plain","data":"VGVzdGluZw=="}}}},
+			{"id":"..","key":"..","value":{"rev":"1-c"},"doc":{"_id":"..","_rev":"1-c","_attachments":{"..":{"content_type":"text/plain","data":"VGVzdGluZw=="}}}}
+		]}`,
+	})
+	defer s.Close()
+	test.ValidateCmdTest([]string{"dump"})(t, test.CmdTest{
+		Args: []string{s.URL + "/foo", "--" + flagAttachmentMode, attModeSidecar, "--" + flagAttachmentDir, dir},
+		Stdout: `{"_attachments":{"foo.txt":{"content_type":"text/plain","sidecar":"a%2Fb/foo.txt","stub":true}},"_id":"a/b","_rev":"1-b"}` + "\n" +
+			`{"_attachments":{"..":{"content_type":"text/plain","sidecar":"%2E%2E/%2E%2E","stub":true}},"_id":"..","_rev":"1-c"}` + "\n",
+	})
+	for _, path := range []string{filepath.Join(dir, "a%2Fb", "foo.txt"), filepath.Join(dir, "%2E%2E", "%2E%2E")} {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := diff.Text("Testing", string(content)); d != nil {
+			t.Error(d)
+		}
+	}
+}
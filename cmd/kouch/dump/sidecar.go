@@ -0,0 +1,75 @@
+package dump
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/errors"
+)
+
+// writeSidecars writes any inline attachments in doc to separate files below
+// d.attDir, replacing the inline data with a stub which references the file.
+// The file's path is relative to d.attDir.
+func (d *dumper) writeSidecars(docID string, doc json.RawMessage) (json.RawMessage, error) {
+	var parsed map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &parsed); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitWeirdReply, err)
+	}
+	rawAtts, ok := parsed["_attachments"]
+	if !ok {
+		return doc, nil
+	}
+	var atts map[string]map[string]interface{}
+	if err := json.Unmarshal(rawAtts, &atts); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitWeirdReply, err)
+	}
+	for filename, att := range atts {
+		data, ok := att["data"].(string)
+		if !ok {
+			continue
+		}
+		content, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, errors.WrapExitError(chttp.ExitWeirdReply, err)
+		}
+		relPath := filepath.Join(sidecarSegment(docID), sidecarSegment(filename))
+		path := filepath.Join(d.attDir, relPath)
+		if rel, err := filepath.Rel(d.attDir, path); err != nil || rel != relPath {
+			return nil, errors.NewExitError(chttp.ExitWriteError, "Attachment '%s' of document '%s' cannot be written below %s", filename, docID, d.attDir)
+		}
+		if err := writeFile(path, content); err != nil {
+			return nil, err
+		}
+		delete(att, "data")
+		att["stub"] = true
+		att["sidecar"] = filepath.ToSlash(relPath)
+	}
+	var err error
+	if parsed["_attachments"], err = json.Marshal(atts); err != nil {
+		return nil, err
+	}
+	return json.Marshal(parsed)
+}
+
+// sidecarSegment encodes a document ID or attachment name as a single path
+// segment. Slashes are escaped by url.PathEscape, but dots are not, so
+// segments consisting only of dots, such as `..`, are escaped too.
+func sidecarSegment(name string) string {
+	if strings.Trim(name, ".") == "" {
+		return strings.Replace(name, ".", "%2E", -1)
+	}
+	return url.PathEscape(name)
+}
+
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	return errors.WrapExitError(chttp.ExitWriteError, ioutil.WriteFile(path, content, 0644))
+}
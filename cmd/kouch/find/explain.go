@@ -0,0 +1,46 @@
+package find
+
+import (
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registry.Register(nil, explainCmd)
+}
+
+func explainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain [target]",
+		Short: "Explains how a Mango query would be executed.",
+		Long: "Shows which index a Mango query would use, and the parameters with which it would be executed, using _explain.\n\n" +
+			"The selector is read from the input, as for 'kouch find'.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: explainQueryCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}.")
+	addQueryFlags(f)
+	return cmd
+}
+
+func explainQueryCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, query, err := queryOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if done, err := util.ExplainSelector(ctx, cmd.Flags(), query["selector"]); done || err != nil {
+		return err
+	}
+	if err := validateTarget(o.Target); err != nil {
+		return err
+	}
+	o.Body = chttp.EncodeBody(query)
+	return util.ChttpDo(ctx, http.MethodPost, util.EndpointPath(o, "_explain"), o)
+}
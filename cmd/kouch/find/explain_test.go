@@ -0,0 +1,48 @@
+package find
+
+import (
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func TestExplainCmd(t *testing.T) {
+	type explainTest struct {
+		test.CmdTest
+		requests func() []string
+		expected []string
+	}
+	tests := testy.NewTable()
+	tests.Add("no selector", explainTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost/foo", "-d", ""},
+			Err:    "No selector provided",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Body: `{"dbname":"foo","index":{"ddoc":null,"name":"_all_docs"}}`})
+		tests.Cleanup(s.Close)
+		return explainTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "--" + kouch.FlagDataYAML, "type: user", "--" + flagUseIndex, "app"},
+				Stdout: `{"dbname":"foo","index":{"ddoc":null,"name":"_all_docs"}}`,
+			},
+			requests: s.Requests,
+			expected: []string{`POST /foo/_explain {"selector":{"type":"user"},"use_index":"app"}` + "\n"},
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt explainTest) {
+		test.ValidateCmdTest([]string{"explain"})(t, tt.CmdTest)
+		if tt.requests != nil {
+			if d := diff.Interface(tt.expected, tt.requests()); d != nil {
+				t.Errorf("Unexpected requests:\n%s", d)
+			}
+		}
+	})
+}
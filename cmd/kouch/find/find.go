@@ -0,0 +1,299 @@
+package find
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	kio "github.com/go-kivik/kouch/io"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagFields         = "fields"
+	flagSort           = "sort"
+	flagUseIndex       = "use-index"
+	flagR              = "r"
+	flagBookmark       = "bookmark"
+	flagExecutionStats = "execution-stats"
+	flagAll            = "all"
+)
+
+func init() {
+	registry.Register(nil, findCmd)
+}
+
+func findCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "find [target]",
+		Short: "Finds documents using a Mango query.",
+		Long: "Finds documents using a declarative Mango query, with _find.\n\n" +
+			"The selector is read from the input, and may be provided with --" + kouch.FlagDataYAML + " or --" + kouch.FlagDataJSON + ", or on stdin. " +
+			"If the input contains a `selector` field, it is treated as a complete query, to which the command line options are added. " +
+			"Alternatively, simple selectors may be built with --" + kouch.FlagWhere + ", in which case no input is read unless a data option is also given.\n\n" +
+			"Each matching document is written as a separate document, as soon as it is received. " +
+			"Unless --" + flagAll + " is given, a final document containing the `bookmark`, and any `execution_stats`, follows the results.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: findDocsCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}.")
+	addQueryFlags(f)
+	f.String(flagBookmark, "", "A bookmark from a previous query, from which to continue.")
+	f.Bool(flagExecutionStats, false, "Include execution statistics in the response.")
+	f.Bool(flagAll, false, "Follow bookmarks to fetch all matching documents, one page at a time.")
+	return cmd
+}
+
+// addQueryFlags adds the flags which modify the query itself.
+func addQueryFlags(f *pflag.FlagSet) {
+	util.AddWhereFlags(f)
+	f.String(kouch.FlagPartition, "", "Limit the query to the specified partition of a partitioned database.")
+	f.StringSlice(flagFields, nil, "The fields to return for each document.")
+	f.StringSlice(flagSort, nil, "The fields by which to sort, in the format `field` or `field:desc`.")
+	f.Int(kouch.FlagLimit, 0, "The maximum number of documents to return, per page.")
+	f.Int(kouch.FlagSkip, 0, "Skip this number of documents before returning results.")
+	f.String(flagUseIndex, "", "The index to use, in the format `ddoc` or `ddoc/name`.")
+	f.Int(flagR, 0, "Read quorum needed for the result.")
+}
+
+type finder struct {
+	o     *kouch.Options
+	query map[string]interface{}
+	all   bool
+}
+
+func findDocsCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	f, err := findOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if done, err := util.ExplainSelector(ctx, cmd.Flags(), f.query["selector"]); done || err != nil {
+		return err
+	}
+	if err := validateTarget(f.o.Target); err != nil {
+		return err
+	}
+	return f.find(ctx)
+}
+
+func findOpts(ctx context.Context, flags *pflag.FlagSet) (*finder, error) {
+	o, query, err := queryOpts(ctx, flags)
+	if err != nil {
+		return nil, err
+	}
+	f := &finder{o: o, query: query}
+	bookmark, err := flags.GetString(flagBookmark)
+	if err != nil {
+		return nil, err
+	}
+	if bookmark != "" {
+		query["bookmark"] = bookmark
+	}
+	stats, err := flags.GetBool(flagExecutionStats)
+	if err != nil {
+		return nil, err
+	}
+	if stats {
+		query["execution_stats"] = true
+	}
+	if f.all, err = flags.GetBool(flagAll); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// queryOpts returns the options and the query, read from the input and
+// modified by the command line flags.
+func queryOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, map[string]interface{}, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, nil, err
+	}
+	query, err := buildQuery(ctx, flags)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := setQueryFlags(query, flags); err != nil {
+		return nil, nil, err
+	}
+	return o, query, nil
+}
+
+// buildQuery builds the query from the input, and any --where conditions.
+func buildQuery(ctx context.Context, flags *pflag.FlagSet) (map[string]interface{}, error) {
+	where, err := util.WhereSelector(flags)
+	if err != nil {
+		return nil, err
+	}
+	if where != nil && !dataProvided(flags) {
+		return map[string]interface{}{"selector": where}, nil
+	}
+	query, err := readQuery(kouch.Input(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if where != nil {
+		query["selector"] = util.AndSelectors(query["selector"], where)
+	}
+	return query, nil
+}
+
+// dataProvided returns true if any of the data options were given.
+func dataProvided(flags *pflag.FlagSet) bool {
+	for _, flag := range []string{kouch.FlagData, kouch.FlagDataJSON, kouch.FlagDataYAML} {
+		if flags.Changed(flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// readQuery reads a selector, or a complete query, in YAML or JSON format,
+// from r.
+func readQuery(r io.Reader) (map[string]interface{}, error) {
+	data, err := kio.DecodeData(r)
+	if err == io.EOF {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No selector provided")
+	}
+	if err != nil {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid selector: %s", err)
+	}
+	selector, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid selector: expected an object")
+	}
+	if _, ok := selector["selector"]; ok {
+		return selector, nil
+	}
+	return map[string]interface{}{"selector": selector}, nil
+}
+
+func setQueryFlags(query map[string]interface{}, flags *pflag.FlagSet) error {
+	fields, err := flags.GetStringSlice(flagFields)
+	if err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		query["fields"] = fields
+	}
+	sort, err := flags.GetStringSlice(flagSort)
+	if err != nil {
+		return err
+	}
+	if len(sort) > 0 {
+		if query["sort"], err = util.ParseSort(sort); err != nil {
+			return err
+		}
+	}
+	for flag, field := range map[string]string{kouch.FlagLimit: "limit", kouch.FlagSkip: "skip", flagR: "r"} {
+		if !flags.Changed(flag) {
+			continue
+		}
+		v, err := flags.GetInt(flag)
+		if err != nil {
+			return err
+		}
+		query[field] = v
+	}
+	index, err := flags.GetString(flagUseIndex)
+	if err != nil {
+		return err
+	}
+	if index != "" {
+		query["use_index"] = parseUseIndex(index)
+	}
+	return nil
+}
+
+// parseUseIndex converts an index specification, in the format `ddoc` or
+// `ddoc/name`, to the format expected by _find.
+func parseUseIndex(index string) interface{} {
+	index = strings.TrimPrefix(index, "_design/")
+	if parts := strings.SplitN(index, "/", 2); len(parts) == 2 {
+		return parts
+	}
+	return index
+}
+
+func (f *finder) find(ctx context.Context) error {
+	w, err := kio.NewDocWriter(ctx)
+	if err != nil {
+		return err
+	}
+	c, err := f.o.NewClient()
+	if err != nil {
+		return err
+	}
+	for {
+		count, trailer, err := f.page(ctx, c, w)
+		if err != nil {
+			return err
+		}
+		if warning, ok := trailer["warning"]; ok {
+			var msg string
+			_ = json.Unmarshal(warning, &msg)
+			_, _ = fmt.Fprintf(os.Stderr, "Warning: %s\n", msg)
+		}
+		if !f.all {
+			tr, err := json.Marshal(trailer)
+			if err != nil {
+				return err
+			}
+			return w.WriteDoc(tr)
+		}
+		if stats, ok := trailer["execution_stats"]; ok {
+			_, _ = fmt.Fprintf(os.Stderr, "%s\n", stats)
+		}
+		var bookmark string
+		_ = json.Unmarshal(trailer["bookmark"], &bookmark)
+		if count == 0 || bookmark == "" || bookmark == f.query["bookmark"] {
+			return nil
+		}
+		f.query["bookmark"] = bookmark
+		// The bookmark accounts for any skipped documents.
+		delete(f.query, "skip")
+	}
+}
+
+// page fetches a single page of results, writing each document to w, and
+// returning the number of documents, and the remaining fields of the
+// response.
+func (f *finder) page(ctx context.Context, c *chttp.Client, w *kio.DocWriter) (int, map[string]json.RawMessage, error) {
+	f.o.Body = chttp.EncodeBody(f.query)
+	res, err := c.DoReq(ctx, http.MethodPost, util.EndpointPath(f.o, "_find"), f.o.Options)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err = chttp.ResponseError(res); err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close() // nolint: errcheck
+	var count int
+	trailer, err := util.StreamArray(res.Body, "docs", func(doc json.RawMessage) error {
+		count++
+		return w.WriteDoc(doc)
+	})
+	return count, trailer, err
+}
+
+func validateTarget(t *kouch.Target) error {
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
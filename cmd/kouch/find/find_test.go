@@ -0,0 +1,175 @@
+package find
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestParseUseIndex(t *testing.T) {
+	tests := map[string]interface{}{
+		"foo":                 "foo",
+		"_design/foo":         "foo",
+		"foo/bar":             []string{"foo", "bar"},
+		"_design/foo/bar":     []string{"foo", "bar"},
+		"foo/bar/with/slashs": []string{"foo", "bar/with/slashs"},
+	}
+	for index, expected := range tests {
+		if d := diff.Interface(expected, parseUseIndex(index)); d != nil {
+			t.Errorf("%s: %s", index, d)
+		}
+	}
+}
+
+func TestFindCmd(t *testing.T) {
+	type findTest struct {
+		test.CmdTest
+		requests func() []string
+		expected []string
+	}
+	tests := testy.NewTable()
+	tests.Add("no database", findTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"--" + kouch.FlagDataJSON, "{}"},
+			Err:    "No database name provided",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("invalid selector", findTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost/foo", "-d", "[]"},
+			Err:    "Invalid selector: expected an object",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("invalid sort", findTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost/foo", "-d", "{}", "--" + flagSort, "foo:bar"},
+			Err:    "Invalid sort direction 'bar' for field 'foo'. Supported options: `asc`, `desc`",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("yaml selector", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Body: `{"docs":[{"_id":"a"},{"_id":"b"}],"bookmark":"xyz","warning":"No matching index found"}`})
+		tests.Cleanup(s.Close)
+		return findTest{
+			CmdTest: test.CmdTest{
+				Args: []string{s.URL + "/foo", "--" + kouch.FlagDataYAML, "type: user", "--" + flagFields, "_id,name",
+					"--" + flagSort, "name:desc", "--" + kouch.FlagLimit, "2", "--" + kouch.FlagSkip, "0",
+					"--" + flagUseIndex, "_design/app/by-name", "--" + flagR, "2", "--" + flagBookmark, "abc"},
+				Stdout: `{"_id":"a"}` + "\n" + `{"_id":"b"}` + "\n" + `{"bookmark":"xyz","warning":"No matching index found"}` + "\n",
+				Stderr: "Warning: No matching index found\n",
+			},
+			requests: s.Requests,
+			expected: []string{`POST /foo/_find {"bookmark":"abc","fields":["_id","name"],"limit":2,"r":2,"selector":{"type":"user"},"skip":0,"sort":[{"name":"desc"}],"use_index":["app","by-name"]}` + "\n"},
+		}
+	})
+	tests.Add("yaml input", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Body: `{"docs":[],"bookmark":"nil"}`})
+		tests.Cleanup(s.Close)
+		return findTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "-d", "type: user\nage:\n  $gt: 30\n"},
+				Stdout: `{"bookmark":"nil"}` + "\n",
+			},
+			requests: s.Requests,
+			expected: []string{`POST /foo/_find {"selector":{"age":{"$gt":30},"type":"user"}}` + "\n"},
+		}
+	})
+	tests.Add("complete query, partitioned", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Body: `{"docs":[],"bookmark":"nil","execution_stats":{"total_docs_examined":0}}`})
+		tests.Cleanup(s.Close)
+		return findTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "--" + kouch.FlagPartition, "bar", "-d", `{"selector":{"x":1},"limit":5}`, "--" + flagExecutionStats},
+				Stdout: `{"bookmark":"nil","execution_stats":{"total_docs_examined":0}}` + "\n",
+			},
+			requests: s.Requests,
+			expected: []string{`POST /foo/_partition/bar/_find {"execution_stats":true,"limit":5,"selector":{"x":1}}` + "\n"},
+		}
+	})
+	tests.Add("all", func(t *testing.T) interface{} {
+		s := test.NewServer(t,
+			test.Response{Body: `{"docs":[{"_id":"a"}],"bookmark":"one"}`},
+			test.Response{Body: `{"docs":[{"_id":"b"}],"bookmark":"two"}`},
+			test.Response{Body: `{"docs":[],"bookmark":"two"}`},
+		)
+		tests.Cleanup(s.Close)
+		return findTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "-d", `{"x":1}`, "--" + kouch.FlagSkip, "3", "--" + flagAll},
+				Stdout: `{"_id":"a"}` + "\n" + `{"_id":"b"}` + "\n",
+			},
+			requests: s.Requests,
+			expected: []string{
+				`POST /foo/_find {"selector":{"x":1},"skip":3}` + "\n",
+				`POST /foo/_find {"bookmark":"one","selector":{"x":1}}` + "\n",
+				`POST /foo/_find {"bookmark":"two","selector":{"x":1}}` + "\n",
+			},
+		}
+	})
+	tests.Add("where", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Body: `{"docs":[],"bookmark":"nil"}`})
+		tests.Cleanup(s.Close)
+		return findTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "--" + kouch.FlagWhere, "type=user", "--" + kouch.FlagWhere, "tags~admin"},
+				Stdout: `{"bookmark":"nil"}` + "\n",
+			},
+			requests: s.Requests,
+			expected: []string{`POST /foo/_find {"selector":{"tags":{"$all":["admin"]},"type":{"$eq":"user"}}}` + "\n"},
+		}
+	})
+	tests.Add("where and data", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Body: `{"docs":[],"bookmark":"nil"}`})
+		tests.Cleanup(s.Close)
+		return findTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "-d", `{"selector":{"x":1},"limit":1}`, "--" + kouch.FlagWhere, "y<2"},
+				Stdout: `{"bookmark":"nil"}` + "\n",
+			},
+			requests: s.Requests,
+			expected: []string{`POST /foo/_find {"limit":1,"selector":{"$and":[{"x":1},{"y":{"$lt":2}}]}}` + "\n"},
+		}
+	})
+	tests.Add("explain selector", findTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"--" + kouch.FlagWhere, `name="30"`, "--" + kouch.FlagExplainSelector, "-F", "yaml"},
+			Stdout: "name:\n  $eq: \"30\"\n",
+		},
+	})
+	tests.Add("invalid where", findTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost/foo", "--" + kouch.FlagWhere, "oink"},
+			Err:    "Invalid condition 'oink': no operator found",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("server error", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Status: http.StatusBadRequest, Body: `{"error":"bad_request","reason":"invalid selector"}`})
+		tests.Cleanup(s.Close)
+		return findTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "-d", `{"x":1}`},
+				Err:    "Bad Request: invalid selector",
+				Status: chttp.ExitNotRetrieved,
+			},
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt findTest) {
+		test.ValidateCmdTest([]string{"find"})(t, tt.CmdTest)
+		if tt.requests != nil {
+			if d := diff.Interface(tt.expected, tt.requests()); d != nil {
+				t.Errorf("Unexpected requests:\n%s", d)
+			}
+		}
+	})
+}
@@ -0,0 +1,296 @@
+package follow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagCheckpoint = "checkpoint"
+	flagExec       = "exec"
+	flagMaxBackoff = "max-backoff"
+)
+
+const defaultHeartbeat = 30000
+
+// Backoff limits between reconnection attempts. minBackoff is a variable, to
+// allow tests to run quickly.
+var minBackoff = time.Second
+
+const defaultMaxBackoff = time.Minute
+
+func init() {
+	registry.Register(nil, followCmd)
+}
+
+func followCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "follow [target]",
+		Short: "Runs a command for each change to a database.",
+		Long: "Tails the continuous changes feed of a database, running a command for each change.\n\n" +
+			"The command is run with the shell. The changed document is provided on stdin, and the following environment variables are set:\n\n" +
+			"    KOUCH_DATABASE  The database name\n" +
+			"    KOUCH_SEQ       The update sequence of the change\n" +
+			"    KOUCH_ID        The document ID\n" +
+			"    KOUCH_REV       The document's current revision\n" +
+			"    KOUCH_DELETED   `true` if the document was deleted, otherwise `false`\n\n" +
+			"After each successful command, the sequence is written to the checkpoint file, if provided, so that the follower resumes from where it left off when restarted. " +
+			"If the command fails, kouch exits without updating the checkpoint.\n\n" +
+			"Network errors, and server errors, cause kouch to reconnect, with exponential backoff.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: followChangesCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}.")
+	f.String(flagCheckpoint, "", "File in which the last processed sequence is stored.")
+	f.String(flagExec, "", "Command to run for each change.")
+	f.String(kouch.FlagSince, "", "Start from the change immediately after the given update sequence, when no checkpoint exists. Use `now` to receive only new changes.")
+	f.String(kouch.FlagFilter, "", "The filter function, in the format `ddoc/name`, to apply to the feed.")
+	f.Int(kouch.FlagHeartbeat, defaultHeartbeat, "Period, in milliseconds, after which an empty line is sent, to keep the connection alive.")
+	f.Duration(flagMaxBackoff, defaultMaxBackoff, "Maximum delay between reconnection attempts.")
+	return cmd
+}
+
+type follower struct {
+	o          *kouch.Options
+	checkpoint string
+	command    string
+	maxBackoff time.Duration
+}
+
+func followChangesCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	f, err := followOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	return f.follow(ctx)
+}
+
+func followOpts(ctx context.Context, flags *pflag.FlagSet) (*follower, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	if e := o.SetParams(flags, kouch.FlagSince, kouch.FlagFilter, kouch.FlagHeartbeat); e != nil {
+		return nil, e
+	}
+	o.Query().Set("feed", "continuous")
+	o.Query().Set("include_docs", "true")
+	if o.Query().Get("heartbeat") == "" {
+		o.Query().Set("heartbeat", strconv.Itoa(defaultHeartbeat))
+	}
+	f := &follower{o: o}
+	if f.checkpoint, err = flags.GetString(flagCheckpoint); err != nil {
+		return nil, err
+	}
+	if f.command, err = flags.GetString(flagExec); err != nil {
+		return nil, err
+	}
+	if f.command == "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s is required", flagExec)
+	}
+	if f.maxBackoff, err = flags.GetDuration(flagMaxBackoff); err != nil {
+		return nil, err
+	}
+	if o.Database == "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	return f, nil
+}
+
+type change struct {
+	Seq     json.RawMessage `json:"seq"`
+	ID      string          `json:"id"`
+	Deleted bool            `json:"deleted"`
+	Changes []struct {
+		Rev string `json:"rev"`
+	} `json:"changes"`
+	Doc     json.RawMessage `json:"doc"`
+	LastSeq json.RawMessage `json:"last_seq"`
+}
+
+func (f *follower) follow(ctx context.Context) error {
+	since, err := f.readCheckpoint()
+	if err != nil {
+		return err
+	}
+	if since != "" {
+		f.o.Query().Set("since", since)
+	}
+	c, err := f.o.NewClient()
+	if err != nil {
+		return err
+	}
+	backoff := minBackoff
+	for {
+		progressed, err := f.tail(ctx, c)
+		if _, ok := err.(*temporaryError); err != nil && !ok {
+			return err
+		}
+		if progressed {
+			backoff = minBackoff
+		}
+		if err == nil && progressed {
+			// The server closed the feed normally; reconnect immediately.
+			continue
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if backoff *= 2; backoff > f.maxBackoff {
+			backoff = f.maxBackoff
+		}
+	}
+}
+
+// temporaryError wraps network and server errors, after which the follower
+// reconnects.
+type temporaryError struct {
+	error
+}
+
+func (e *temporaryError) ExitStatus() int {
+	return kouch.ExitStatus(e.error)
+}
+
+// tail reads the changes feed until it ends, or an error occurs. It returns
+// true if any changes were processed.
+func (f *follower) tail(ctx context.Context, c *chttp.Client) (bool, error) {
+	res, err := c.DoReq(ctx, http.MethodGet, util.DatabasePath(f.o)+"/_changes", f.o.Options)
+	if err != nil {
+		if kivik.StatusCode(err) == kivik.StatusBadAPICall {
+			return false, err
+		}
+		return false, &temporaryError{err}
+	}
+	if err = chttp.ResponseError(res); err != nil {
+		if kivik.StatusCode(err) >= http.StatusInternalServerError {
+			return false, &temporaryError{err}
+		}
+		return false, err
+	}
+	defer res.Body.Close() // nolint: errcheck
+	var progressed bool
+	var fatal error
+	err = util.StreamLines(res.Body, func(line []byte) error {
+		fatal = f.process(ctx, line)
+		progressed = progressed || fatal == nil
+		return fatal
+	})
+	if fatal != nil {
+		return progressed, fatal
+	}
+	if err != nil {
+		return progressed, &temporaryError{err}
+	}
+	return progressed, nil
+}
+
+// process handles a single line of the changes feed.
+func (f *follower) process(ctx context.Context, line []byte) error {
+	var ch change
+	if err := json.Unmarshal(line, &ch); err != nil {
+		return errors.WrapExitError(chttp.ExitWeirdReply, err)
+	}
+	if ch.LastSeq != nil {
+		return nil
+	}
+	if err := f.exec(ctx, &ch); err != nil {
+		return err
+	}
+	seq := seqString(ch.Seq)
+	if err := f.writeCheckpoint(seq); err != nil {
+		return err
+	}
+	f.o.Query().Set("since", seq)
+	return nil
+}
+
+// seqString converts a sequence, which may be a JSON string (CouchDB 2.x+) or
+// number (CouchDB 1.x), to a string.
+func seqString(seq json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(seq, &s); err == nil {
+		return s
+	}
+	return string(seq)
+}
+
+func (f *follower) exec(ctx context.Context, ch *change) error {
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", f.command)
+	cmd.Stdin = bytes.NewReader(ch.Doc)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	var rev string
+	if len(ch.Changes) > 0 {
+		rev = ch.Changes[0].Rev
+	}
+	cmd.Env = append(os.Environ(),
+		"KOUCH_DATABASE="+f.o.Database,
+		"KOUCH_SEQ="+seqString(ch.Seq),
+		"KOUCH_ID="+ch.ID,
+		"KOUCH_REV="+rev,
+		"KOUCH_DELETED="+strconv.FormatBool(ch.Deleted),
+	)
+	if err := cmd.Run(); err != nil {
+		return errors.NewExitError(chttp.ExitUnknownFailure, "Command failed for document '%s': %s", ch.ID, err)
+	}
+	return nil
+}
+
+func (f *follower) readCheckpoint() (string, error) {
+	if f.checkpoint == "" {
+		return "", nil
+	}
+	seq, err := ioutil.ReadFile(f.checkpoint)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	return strings.TrimSpace(string(seq)), nil
+}
+
+// writeCheckpoint atomically replaces the checkpoint file, by writing to a
+// temporary file in the same directory, then renaming it.
+func (f *follower) writeCheckpoint(seq string) error {
+	if f.checkpoint == "" {
+		return nil
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(f.checkpoint), "."+filepath.Base(f.checkpoint))
+	if err != nil {
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	if _, err := tmp.WriteString(seq + "\n"); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	return errors.WrapExitError(chttp.ExitWriteError, os.Rename(tmp.Name(), f.checkpoint))
+}
@@ -0,0 +1,139 @@
+package follow
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestFollowCmd(t *testing.T) {
+	minBackoff = time.Millisecond
+	type followTest struct {
+		test.CmdTest
+		requests   func() []string
+		expected   []string
+		checkpoint string
+		seq        string
+	}
+	tmpdir, err := ioutil.TempDir("", "kouch-follow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // nolint: errcheck
+
+	tests := testy.NewTable()
+	tests.Add("no command", followTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost/foo"},
+			Err:    "--exec is required",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("no database", followTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"--" + flagExec, "cat"},
+			Err:    "No database name provided",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("reconnect and checkpoint", func(t *testing.T) interface{} {
+		s := test.NewServer(t,
+			test.Response{Status: http.StatusServiceUnavailable, Body: `{"error":"unavailable"}`},
+			test.Response{Status: http.StatusOK, Body: `{"seq":"1-a","id":"a","changes":[{"rev":"1-x"}],"doc":{"_id":"a","_rev":"1-x"}}
+
+{"seq":"2-a","id":"b","changes":[{"rev":"2-y"}],"deleted":true,"doc":{"_id":"b","_rev":"2-y","_deleted":true}}
+{"last_seq":"2-a","pending":0}
+`},
+			test.Response{Status: http.StatusNotFound, Body: `{"error":"not_found","reason":"Database does not exist."}`},
+		)
+		tests.Cleanup(s.Close)
+		checkpoint := filepath.Join(tmpdir, "reconnect.seq")
+		return followTest{
+			CmdTest: test.CmdTest{
+				Args: []string{s.URL + "/foo", "--" + flagCheckpoint, checkpoint,
+					"--" + flagExec, `cat; echo " $KOUCH_DATABASE $KOUCH_ID $KOUCH_SEQ $KOUCH_REV $KOUCH_DELETED"`},
+				Stdout: `{"_id":"a","_rev":"1-x"} foo a 1-a 1-x false
+{"_id":"b","_rev":"2-y","_deleted":true} foo b 2-a 2-y true
+`,
+				Err:    "Not Found: Database does not exist.",
+				Status: chttp.ExitNotRetrieved,
+			},
+			requests: s.Requests,
+			expected: []string{
+				"GET /foo/_changes?feed=continuous&heartbeat=30000&include_docs=true ",
+				"GET /foo/_changes?feed=continuous&heartbeat=30000&include_docs=true ",
+				"GET /foo/_changes?feed=continuous&heartbeat=30000&include_docs=true&since=2-a ",
+			},
+			checkpoint: checkpoint,
+			seq:        "2-a\n",
+		}
+	})
+	tests.Add("resume from checkpoint", func(t *testing.T) interface{} {
+		s := test.NewServer(t,
+			test.Response{Status: http.StatusUnauthorized, Body: `{"error":"unauthorized","reason":"Name or password is incorrect."}`},
+		)
+		tests.Cleanup(s.Close)
+		checkpoint := filepath.Join(tmpdir, "resume.seq")
+		if err := ioutil.WriteFile(checkpoint, []byte("5-x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return followTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "--" + flagCheckpoint, checkpoint, "--" + flagExec, "cat", "--" + kouch.FlagSince, "now"},
+				Err:    "Unauthorized: Name or password is incorrect.",
+				Status: chttp.ExitNotRetrieved,
+			},
+			requests: s.Requests,
+			expected: []string{
+				"GET /foo/_changes?feed=continuous&heartbeat=30000&include_docs=true&since=5-x ",
+			},
+			checkpoint: checkpoint,
+			seq:        "5-x\n",
+		}
+	})
+	tests.Add("command fails", func(t *testing.T) interface{} {
+		s := test.NewServer(t,
+			test.Response{Status: http.StatusOK, Body: `{"seq":"1-a","id":"a","changes":[{"rev":"1-x"}],"doc":{"_id":"a","_rev":"1-x"}}` + "\n"},
+		)
+		tests.Cleanup(s.Close)
+		checkpoint := filepath.Join(tmpdir, "fails.seq")
+		return followTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "--" + flagCheckpoint, checkpoint, "--" + flagExec, "exit 3", "--" + kouch.FlagSince, "0"},
+				Err:    "Command failed for document 'a': exit status 3",
+				Status: chttp.ExitUnknownFailure,
+			},
+			requests: s.Requests,
+			expected: []string{
+				"GET /foo/_changes?feed=continuous&heartbeat=30000&include_docs=true&since=0 ",
+			},
+			checkpoint: checkpoint,
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt followTest) {
+		test.ValidateCmdTest([]string{"follow"})(t, tt.CmdTest)
+		if tt.requests != nil {
+			if d := diff.Interface(tt.expected, tt.requests()); d != nil {
+				t.Errorf("Unexpected requests:\n%s", d)
+			}
+		}
+		if tt.checkpoint != "" {
+			seq, _ := ioutil.ReadFile(tt.checkpoint)
+			if d := diff.Text(tt.seq, string(seq)); d != nil {
+				t.Errorf("Unexpected checkpoint:\n%s", d)
+			}
+		}
+	})
+}
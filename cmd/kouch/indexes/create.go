@@ -0,0 +1,131 @@
+package indexes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	kio "github.com/go-kivik/kouch/io"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagField = "field"
+	flagName  = "name"
+)
+
+func init() {
+	registry.Register([]string{"create"}, createIndexCmd)
+}
+
+func createIndexCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "index [target]",
+		Short: "Creates a Mango index.",
+		Long: "Creates a Mango index, using _index.\n\n" +
+			"The index is defined with --" + flagField + ", or else read from the input, which may be provided with --" + kouch.FlagDataYAML + " or --" + kouch.FlagDataJSON + ", or on stdin. " +
+			"If the input contains an `index` field, it is treated as a complete request, otherwise as the index definition itself.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: createIndexDefCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}.")
+	f.StringArray(flagField, nil, "A field to index, in the format `field` or `field:desc`. May be repeated.")
+	f.String(kouch.FlagDesignDoc, "", "The design document in which to create the index.")
+	f.String(flagName, "", "The name of the index.")
+	f.Bool(kouch.FlagPartitioned, false, "Whether the index is partitioned. Defaults to the partitioning of the database.")
+	f.BoolP(kouch.FlagYes, kouch.FlagShortYes, false, "Do not prompt for confirmation when using a protected context.")
+	return cmd
+}
+
+func createIndexDefCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := createIndexOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if err := util.ConfirmMutation(o, cmd.Flags(), fmt.Sprintf("You are about to create an index in the database '%s'.", o.Database), o.Database); err != nil {
+		return err
+	}
+	return util.ChttpDo(ctx, http.MethodPost, util.DatabasePath(o)+"/_index", o)
+}
+
+func createIndexOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateTarget(o.Target); err != nil {
+		return nil, err
+	}
+	def, err := indexDef(ctx, flags)
+	if err != nil {
+		return nil, err
+	}
+	o.Body = chttp.EncodeBody(def)
+	return o, nil
+}
+
+// indexDef builds the _index request body from the flags, or the input.
+func indexDef(ctx context.Context, flags *pflag.FlagSet) (map[string]interface{}, error) {
+	fields, err := flags.GetStringArray(flagField)
+	if err != nil {
+		return nil, err
+	}
+	var def map[string]interface{}
+	if len(fields) > 0 {
+		index, e := util.ParseSort(fields)
+		if e != nil {
+			return nil, e
+		}
+		def = map[string]interface{}{
+			"index": map[string]interface{}{"fields": index},
+		}
+	} else if def, err = readIndexDef(kouch.Input(ctx)); err != nil {
+		return nil, err
+	}
+	for flag, field := range map[string]string{kouch.FlagDesignDoc: "ddoc", flagName: "name"} {
+		v, err := flags.GetString(flag)
+		if err != nil {
+			return nil, err
+		}
+		if v != "" {
+			def[field] = v
+		}
+	}
+	if flags.Changed(kouch.FlagPartitioned) {
+		partitioned, err := flags.GetBool(kouch.FlagPartitioned)
+		if err != nil {
+			return nil, err
+		}
+		def["partitioned"] = partitioned
+	}
+	return def, nil
+}
+
+// readIndexDef reads an index definition, or a complete _index request, in
+// YAML or JSON format, from r.
+func readIndexDef(r io.Reader) (map[string]interface{}, error) {
+	data, err := kio.DecodeData(r)
+	if err == io.EOF {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No index definition provided")
+	}
+	if err != nil {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid index definition: %s", err)
+	}
+	def, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid index definition: expected an object")
+	}
+	if _, ok := def["index"]; ok {
+		return def, nil
+	}
+	return map[string]interface{}{"index": def}, nil
+}
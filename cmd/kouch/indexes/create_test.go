@@ -0,0 +1,82 @@
+package indexes
+
+import (
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func TestCreateIndexCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("no definition", indexTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost/foo", "-d", " "},
+			Err:    "No index definition provided",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("invalid field", indexTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost/foo", "--" + flagField, "name:sideways"},
+			Err:    "Invalid sort direction 'sideways' for field 'name'. Supported options: `asc`, `desc`",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("fields", func(t *testing.T) interface{} {
+		s, request := indexServer(t, tests, `{"result":"created","id":"_design/app","name":"by-name"}`)
+		return indexTest{
+			CmdTest: test.CmdTest{
+				Args: []string{s.URL + "/foo", "--" + flagField, "name", "--" + flagField, "age:desc",
+					"--" + kouch.FlagDesignDoc, "app", "--" + flagName, "by-name", "--" + kouch.FlagPartitioned + "=false"},
+				Stdout: `{"id":"_design/app","name":"by-name","result":"created"}`,
+			},
+			request:  request,
+			expected: `POST /foo/_index {"ddoc":"app","index":{"fields":["name",{"age":"desc"}]},"name":"by-name","partitioned":false}` + "\n",
+		}
+	})
+	tests.Add("yaml definition", func(t *testing.T) interface{} {
+		s, request := indexServer(t, tests, `{"result":"exists"}`)
+		return indexTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "--" + kouch.FlagDataYAML, "fields: [type]\npartial_filter_selector:\n  active: true\n", "--" + flagName, "active"},
+				Stdout: `{"result":"exists"}`,
+			},
+			request:  request,
+			expected: `POST /foo/_index {"index":{"fields":["type"],"partial_filter_selector":{"active":true}},"name":"active"}` + "\n",
+		}
+	})
+	tests.Add("yaml input", func(t *testing.T) interface{} {
+		s, request := indexServer(t, tests, `{"result":"created"}`)
+		return indexTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "-d", "fields: [type]\n"},
+				Stdout: `{"result":"created"}`,
+			},
+			request:  request,
+			expected: `POST /foo/_index {"index":{"fields":["type"]}}` + "\n",
+		}
+	})
+	tests.Add("invalid definition", indexTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost/foo", "-d", "[]"},
+			Err:    "Invalid index definition: expected an object",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("complete request", func(t *testing.T) interface{} {
+		s, request := indexServer(t, tests, `{"result":"created"}`)
+		return indexTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "-d", `{"index":{"fields":["x"]},"type":"json","ddoc":"old"}`, "--" + kouch.FlagDesignDoc, "new"},
+				Stdout: `{"result":"created"}`,
+			},
+			request:  request,
+			expected: `POST /foo/_index {"ddoc":"new","index":{"fields":["x"]},"type":"json"}` + "\n",
+		}
+	})
+
+	runIndexTests(t, tests, []string{"create", "index"})
+}
@@ -0,0 +1,66 @@
+package indexes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"delete"}, deleteIndexCmd)
+}
+
+func deleteIndexCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "index <target> <ddoc> <name>",
+		Short: "Deletes a Mango index.",
+		Long: "Deletes a Mango index, identified by its design document and name, as listed by 'kouch get indexes'.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		Args:              cobra.ExactArgs(3),
+		PersistentPreRunE: util.PositionalArgs,
+		RunE:              deleteIndexDefCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}.")
+	f.BoolP(kouch.FlagYes, kouch.FlagShortYes, false, "Do not prompt for confirmation when using a protected context.")
+	return cmd
+}
+
+func deleteIndexDefCmd(cmd *cobra.Command, args []string) error {
+	ctx := kouch.SetTarget(kouch.GetContext(cmd), args[0])
+	o, err := deleteIndexOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	ddoc := strings.TrimPrefix(args[1], "_design/")
+	name := args[2]
+	if ddoc == "" || name == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "Design document and index name must not be empty")
+	}
+	if err := util.ConfirmMutation(o, cmd.Flags(), fmt.Sprintf("You are about to delete the index '%s/%s' from the database '%s'.", ddoc, name, o.Database), o.Database); err != nil {
+		return err
+	}
+	path := fmt.Sprintf("%s/_index/%s/json/%s", util.DatabasePath(o), url.PathEscape(ddoc), url.PathEscape(name))
+	return util.ChttpDo(ctx, http.MethodDelete, path, o)
+}
+
+func deleteIndexOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateTarget(o.Target); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
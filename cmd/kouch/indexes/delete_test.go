@@ -0,0 +1,40 @@
+package indexes
+
+import (
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func TestDeleteIndexCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("missing arguments", indexTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost/foo", "app"},
+			Err:    "accepts 3 arg(s), received 2",
+			Status: chttp.ExitUnknownFailure,
+		},
+	})
+	tests.Add("no database", indexTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"--root", "http://localhost/", "", "app", "by-name"},
+			Err:    "No database name provided",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		s, request := indexServer(t, tests, `{"ok":true}`)
+		return indexTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "_design/app", "by name"},
+				Stdout: `{"ok":true}`,
+			},
+			request:  request,
+			expected: "DELETE /foo/_index/app/json/by%20name ",
+		}
+	})
+
+	runIndexTests(t, tests, []string{"delete", "index"})
+}
@@ -0,0 +1,61 @@
+package indexes
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getIndexesCmd)
+}
+
+func getIndexesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "indexes [target]",
+		Short: "Lists the Mango indexes of a database.",
+		Long: "Lists the Mango indexes of a database, using _index.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: getIndexListCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}.")
+	return cmd
+}
+
+func getIndexListCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := getIndexesOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	return util.ChttpDo(ctx, http.MethodGet, util.DatabasePath(o)+"/_index", o)
+}
+
+func getIndexesOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateTarget(o.Target); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func validateTarget(t *kouch.Target) error {
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package indexes
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/create"
+	_ "github.com/go-kivik/kouch/cmd/kouch/delete"
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+type indexTest struct {
+	test.CmdTest
+	request  *string
+	expected string
+}
+
+// indexServer returns a server which responds to a single request with
+// response, and records the request in the returned string.
+func indexServer(t *testing.T, tests *testy.Table, response string) (*httptest.Server, *string) {
+	var request string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		request = r.Method + " " + r.URL.RequestURI() + " " + string(body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(response))
+	}))
+	tests.Cleanup(s.Close)
+	return s, &request
+}
+
+func runIndexTests(t *testing.T, tests *testy.Table, args []string) {
+	tests.Run(t, func(t *testing.T, tt indexTest) {
+		test.ValidateCmdTest(args)(t, tt.CmdTest)
+		if tt.request != nil {
+			if d := diff.Text(tt.expected, *tt.request); d != nil {
+				t.Errorf("Unexpected request:\n%s", d)
+			}
+		}
+	})
+}
+
+func TestGetIndexesCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("no database", indexTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"--root", "http://localhost/"},
+			Err:    "No database name provided",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		s, request := indexServer(t, tests, `{"total_rows":1,"indexes":[{"ddoc":null,"name":"_all_docs"}]}`)
+		return indexTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo"},
+				Stdout: `{"indexes":[{"ddoc":null,"name":"_all_docs"}],"total_rows":1}`,
+			},
+			request:  request,
+			expected: "GET /foo/_index ",
+		}
+	})
+
+	runIndexTests(t, tests, []string{"get", "indexes"})
+}
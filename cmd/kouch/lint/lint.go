@@ -0,0 +1,17 @@
+package lint
+
+import (
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registry.Register(nil, lintCmd)
+}
+
+func lintCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lint",
+		Short: "Check a resource for common mistakes.",
+	}
+}
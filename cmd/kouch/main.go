@@ -8,17 +8,37 @@ import (
 	_ "github.com/go-kivik/kouch/cmd/kouch/root"
 
 	// Top-level sub-commands
+	_ "github.com/go-kivik/kouch/cmd/kouch/copy"
 	_ "github.com/go-kivik/kouch/cmd/kouch/create"
 	_ "github.com/go-kivik/kouch/cmd/kouch/delete"
+	_ "github.com/go-kivik/kouch/cmd/kouch/deploy"
+	_ "github.com/go-kivik/kouch/cmd/kouch/dump"
+	_ "github.com/go-kivik/kouch/cmd/kouch/find"
+	_ "github.com/go-kivik/kouch/cmd/kouch/follow"
 	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/lint"
+	_ "github.com/go-kivik/kouch/cmd/kouch/pull"
+	_ "github.com/go-kivik/kouch/cmd/kouch/push"
 	_ "github.com/go-kivik/kouch/cmd/kouch/put"
+	_ "github.com/go-kivik/kouch/cmd/kouch/restore"
+	_ "github.com/go-kivik/kouch/cmd/kouch/tester"
+	_ "github.com/go-kivik/kouch/cmd/kouch/wait"
 
 	// The individual sub-commands
 	_ "github.com/go-kivik/kouch/cmd/kouch/attachments"
+	_ "github.com/go-kivik/kouch/cmd/kouch/bulk"
+	_ "github.com/go-kivik/kouch/cmd/kouch/changes"
 	_ "github.com/go-kivik/kouch/cmd/kouch/config"
 	_ "github.com/go-kivik/kouch/cmd/kouch/database"
+	_ "github.com/go-kivik/kouch/cmd/kouch/design"
 	_ "github.com/go-kivik/kouch/cmd/kouch/documents"
+	_ "github.com/go-kivik/kouch/cmd/kouch/indexes"
+	_ "github.com/go-kivik/kouch/cmd/kouch/partitions"
+	_ "github.com/go-kivik/kouch/cmd/kouch/server"
+	_ "github.com/go-kivik/kouch/cmd/kouch/stats"
+	_ "github.com/go-kivik/kouch/cmd/kouch/tasks"
 	_ "github.com/go-kivik/kouch/cmd/kouch/uuids"
+	_ "github.com/go-kivik/kouch/cmd/kouch/views"
 )
 
 func main() {
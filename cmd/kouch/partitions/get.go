@@ -0,0 +1,61 @@
+package partitions
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getPartitionCmd)
+}
+
+func getPartitionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "partition [target]",
+		Short: "Fetches partition information.",
+		Long: "Fetches information about a partition of a partitioned database.\n\n" +
+			kouch.TargetHelpText(kouch.TargetPartition),
+		RunE: getPartitionInfoCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format {db}/{partition}.")
+	f.String(kouch.FlagPartition, "", "The partition. May be provided with the target in the format {db}/{partition}.")
+	return cmd
+}
+
+func getPartitionInfoCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := getPartitionOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if err := validateTarget(o.Target); err != nil {
+		return err
+	}
+	return util.ChttpDo(ctx, http.MethodGet, util.PartitionPath(o), o)
+}
+
+func getPartitionOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	return util.CommonOptions(ctx, kouch.TargetPartition, flags)
+}
+
+func validateTarget(t *kouch.Target) error {
+	if t.Partition == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No partition provided")
+	}
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package partitions
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestGetPartitionOpts(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("db and partition in target", test.OptionsTest{
+		Args: []string{"foo/bar"},
+		Expected: &kouch.Options{
+			Target:  &kouch.Target{Database: "foo", Partition: "bar"},
+			Options: &chttp.Options{},
+		},
+	})
+	tests.Add("partition from flag", test.OptionsTest{
+		Args: []string{"--" + kouch.FlagDatabase, "foo", "--" + kouch.FlagPartition, "bar"},
+		Expected: &kouch.Options{
+			Target:  &kouch.Target{Database: "foo", Partition: "bar"},
+			Options: &chttp.Options{},
+		},
+	})
+	tests.Add("partition provided twice", test.OptionsTest{
+		Args:   []string{"foo/bar", "--" + kouch.FlagPartition, "bar"},
+		Err:    "Must not use --" + kouch.FlagPartition + " and pass partition as part of the target",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid partition", test.OptionsTest{
+		Args:   []string{"foo/_bar"},
+		Err:    "Invalid partition name '_bar'",
+		Status: chttp.ExitFailedToInitialize,
+	})
+
+	tests.Run(t, test.Options(getPartitionCmd, getPartitionOpts))
+}
+
+func TestGetPartitionCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "No partition provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		s := testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"partition":"bar"}`)),
+		}, func(t *testing.T, req *http.Request) {
+			if req.URL.Path != "/foo/_partition/bar" {
+				t.Errorf("Unexpected req path: %s", req.URL.Path)
+			}
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar"},
+			Stdout: `{"partition":"bar"}`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "partition"}))
+}
@@ -0,0 +1,17 @@
+package pull
+
+import (
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registry.Register(nil, pullCmd)
+}
+
+func pullCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pull",
+		Short: "Pull a resource from the server to the local filesystem.",
+	}
+}
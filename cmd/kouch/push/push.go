@@ -0,0 +1,17 @@
+package push
+
+import (
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registry.Register(nil, pushCmd)
+}
+
+func pushCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "push",
+		Short: "Push a local resource to the server.",
+	}
+}
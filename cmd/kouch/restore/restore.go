@@ -0,0 +1,216 @@
+package restore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/database"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	kio "github.com/go-kivik/kouch/io"
+	"github.com/go-kivik/kouch/kouchio"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagBatchSize = "batch-size"
+	flagWorkers   = "workers"
+	flagRejects   = "rejects"
+	flagCreate    = "create"
+
+	flagAttachmentDir = "attachment-dir"
+)
+
+const (
+	defaultBatchSize = 500
+	defaultWorkers   = 4
+)
+
+func init() {
+	registry.Register(nil, restoreCmd)
+}
+
+func restoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore [target]",
+		Short: "Restores documents to a database.",
+		Long: "Restores documents, such as those written by 'kouch dump', to a database, using _bulk_docs.\n\n" +
+			"Input may be JSON Lines, a JSON array of documents, or (with --" + kouch.FlagInputFormat + "=yaml, or a .yaml file given with --" + kouch.FlagData + ") YAML documents.\n\n" +
+			"Attachments written as sidecar files by 'kouch dump' are read back from --" + flagAttachmentDir + ".\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: restoreDatabaseCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}.")
+	f.String(kouch.FlagInputFormat, kio.InputFormatJSON, "Input format. Supported options: `json`, `yaml`.")
+	f.Int(flagBatchSize, defaultBatchSize, "Number of documents to upload per request.")
+	f.IntP(flagWorkers, "j", defaultWorkers, "Number of concurrent uploads.")
+	f.Bool(kouch.FlagNewEdits, true, "When disabled, document revisions are preserved as provided.")
+	f.String(flagRejects, "", "File to which failed documents are written, as JSON Lines. Defaults to stderr.")
+	f.Bool(flagCreate, false, "Create the database before restoring.")
+	f.String(flagAttachmentDir, "", "Directory from which sidecar attachments, as written by 'kouch dump --attachment-mode=sidecar', are read.")
+	database.AddCreateFlags(f)
+	f.BoolP(kouch.FlagYes, kouch.FlagShortYes, false, "Do not prompt for confirmation when using a protected context.")
+	return cmd
+}
+
+type restorer struct {
+	*util.BulkUploader
+	o       *kouch.Options
+	format  string
+	rejects string
+	create  bool
+	attDir  string
+}
+
+func restoreDatabaseCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	r, err := restoreOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if err := validateTarget(r.o.Target); err != nil {
+		return err
+	}
+	if err := util.ConfirmMutation(r.o, cmd.Flags(), fmt.Sprintf("You are about to restore documents to the database '%s'.", r.o.Database), r.o.Database); err != nil {
+		return err
+	}
+	if r.create {
+		if err := createDatabase(ctx, cmd.Flags()); err != nil {
+			return err
+		}
+	}
+	return r.restore(ctx, kouch.Input(ctx), kouch.Output(ctx))
+}
+
+func restoreOpts(ctx context.Context, flags *pflag.FlagSet) (*restorer, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	r := &restorer{
+		o:            o,
+		BulkUploader: &util.BulkUploader{Path: util.EndpointPath(o, "_bulk_docs")},
+	}
+	if r.format, err = kio.DocInputFormat(flags); err != nil {
+		return nil, err
+	}
+	multi, err := kio.MultipleDataDocs(flags)
+	if err != nil {
+		return nil, err
+	}
+	if multi != "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s accepts a single document; to provide multiple documents, use --%s with --%s", multi, kouch.FlagData, kouch.FlagInputFormat)
+	}
+	if r.BatchSize, err = flags.GetInt(flagBatchSize); err != nil {
+		return nil, err
+	}
+	if r.BatchSize < 1 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must be a positive integer", flagBatchSize)
+	}
+	if r.Workers, err = flags.GetInt(flagWorkers); err != nil {
+		return nil, err
+	}
+	if r.Workers < 1 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must be a positive integer", flagWorkers)
+	}
+	newEdits, err := flags.GetBool(kouch.FlagNewEdits)
+	if err != nil {
+		return nil, err
+	}
+	r.PreserveRevisions = !newEdits
+	if r.rejects, err = flags.GetString(flagRejects); err != nil {
+		return nil, err
+	}
+	if r.create, err = flags.GetBool(flagCreate); err != nil {
+		return nil, err
+	}
+	if r.attDir, err = flags.GetString(flagAttachmentDir); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func validateTarget(t *kouch.Target) error {
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
+
+func createDatabase(ctx context.Context, flags *pflag.FlagSet) error {
+	o, err := database.CreateDatabaseOpts(ctx, flags)
+	if err != nil {
+		return err
+	}
+	c, err := o.NewClient()
+	if err != nil {
+		return err
+	}
+	_, err = c.DoError(ctx, http.MethodPut, util.DatabasePath(o), o.Options)
+	return err
+}
+
+type reject struct {
+	util.BulkResult
+	Doc json.RawMessage `json:"doc,omitempty"`
+}
+
+type summary struct {
+	Read    int `json:"read"`
+	Written int `json:"written"`
+	Failed  int `json:"failed"`
+}
+
+func (r *restorer) restore(ctx context.Context, in io.Reader, out io.Writer) error {
+	docs, err := kio.NewDocReader(in, r.format)
+	if err != nil {
+		return err
+	}
+	if r.Client, err = r.o.NewClient(); err != nil {
+		return err
+	}
+	var rejects io.Writer = os.Stderr
+	if r.rejects != "" {
+		f, e := os.Create(r.rejects)
+		if e != nil {
+			return errors.WrapExitError(chttp.ExitWriteError, e)
+		}
+		defer f.Close() // nolint: errcheck
+		rejects = f
+	}
+	enc := json.NewEncoder(rejects)
+	var failed int
+	r.OnResult = func(doc json.RawMessage, result util.BulkResult) error {
+		if result.Error == "" {
+			return nil
+		}
+		failed++
+		return errors.WrapExitError(chttp.ExitWriteError, enc.Encode(reject{BulkResult: result, Doc: doc}))
+	}
+	read, err := r.Upload(ctx, &attachmentReader{DocSource: docs, attDir: r.attDir, preserveRevs: r.PreserveRevisions})
+	if err != nil {
+		return err
+	}
+	if e := json.NewEncoder(out).Encode(summary{Read: read, Written: read - failed, Failed: failed}); e != nil {
+		return e
+	}
+	if e := kouchio.CloseWriter(out); e != nil {
+		return e
+	}
+	if failed > 0 {
+		return errors.NewExitError(chttp.ExitPostError, "%d of %d documents failed", failed, read)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package restore
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/copy"
+	_ "github.com/go-kivik/kouch/cmd/kouch/create"
+	_ "github.com/go-kivik/kouch/cmd/kouch/delete"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+// bulkServer records the bodies of all requests, and responds with the
+// corresponding response.
+type bulkServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+}
+
+func newBulkServer(t *testing.T, responses map[string]string) *bulkServer {
+	s := &bulkServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path+" "+string(body))
+		s.mu.Unlock()
+		res, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(res))
+	}))
+	return s
+}
+
+func TestRestoreCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid workers", test.CmdTest{
+		Args:   []string{"http://localhost/foo", "--" + flagWorkers, "0"},
+		Err:    "--workers must be a positive integer",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("multiple json documents in --data-json", test.CmdTest{
+		Args:   []string{"http://localhost/foo", "--" + kouch.FlagDataJSON, `{"_id":"a"} {"_id":"b"}`},
+		Err:    "--data-json accepts a single document; to provide multiple documents, use --data with --input-format",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid input format", test.CmdTest{
+		Args:   []string{"http://localhost/foo", "--" + kouch.FlagInputFormat, "xml", "-d", "{}"},
+		Err:    "Unrecognized input format 'xml'",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("rejected document", func(t *testing.T) interface{} {
+		s := newBulkServer(t, map[string]string{
+			"POST /foo/_bulk_docs": `[{"id":"a","error":"conflict","reason":"Document update conflict."},{"id":"b","rev":"1-x","ok":true}]`,
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "-d", `{"_id":"a"} {"_id":"b"}`},
+			Stdout: `{"failed":1,"read":2,"written":1}` + "\n",
+			Stderr: `{"id":"a","error":"conflict","reason":"Document update conflict.","doc":{"_id":"a"}}` + "\n",
+			Err:    "1 of 2 documents failed",
+			Status: chttp.ExitPostError,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"restore"}))
+}
+
+func TestRestoreBatches(t *testing.T) {
+	s := newBulkServer(t, map[string]string{
+		"PUT /foo":             `{"ok":true}`,
+		"POST /foo/_bulk_docs": `[]`,
+	})
+	defer s.Close()
+	test.ValidateCmdTest([]string{"restore"})(t, test.CmdTest{
+		Args: []string{s.URL + "/foo", "--" + flagCreate, "--" + kouch.FlagShards, "2",
+			"--" + flagBatchSize, "2", "--" + flagWorkers, "1", "--" + kouch.FlagNewEdits + "=false",
+			"--" + kouch.FlagInputFormat, "yaml", "-d", "- _id: a\n- _id: b\n- _id: c\n"},
+		Stdout: `{"failed":0,"read":3,"written":3}` + "\n",
+	})
+	expected := []string{
+		"PUT /foo ",
+		`POST /foo/_bulk_docs {"docs":[{"_id":"a"},{"_id":"b"}],"new_edits":false}` + "\n",
+		`POST /foo/_bulk_docs {"docs":[{"_id":"c"}],"new_edits":false}` + "\n",
+	}
+	if d := diff.Interface(expected, s.requests); d != nil {
+		t.Error(d)
+	}
+}
+
+func TestRestoreSidecar(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "kouch-restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // nolint: errcheck
+	if err := os.MkdirAll(filepath.Join(tmpdir, "a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmpdir, "a", "foo.txt"), []byte("Test content\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	const doc = `{"_id":"a","_rev":"1-x","_attachments":{"foo.txt":{"content_type":"text/plain","digest":"md5-x","length":13,"revpos":1,"sidecar":"a/foo.txt","stub":true}}}`
+
+	tests := testy.NewTable()
+	tests.Add("no attachment dir", test.CmdTest{
+		Args:   []string{"http://localhost/foo", "-d", doc},
+		Err:    "Document 'a' references sidecar file 'a/foo.txt'; provide --attachment-dir",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("stub with new_edits=false", test.CmdTest{
+		Args:   []string{"http://localhost/foo", "--" + kouch.FlagNewEdits + "=false", "-d", `{"_id":"a","_rev":"1-x","_attachments":{"foo.txt":{"content_type":"text/plain","stub":true}}}`},
+		Err:    "Document 'a' contains a stub for attachment 'foo.txt'; dump with --attachment-mode=inline or sidecar to preserve attachments",
+		Status: chttp.ExitReadError,
+	})
+	tests.Add("sidecar outside attachment dir", test.CmdTest{
+		Args: []string{"http://localhost/foo", "--" + flagAttachmentDir, filepath.Join(tmpdir, "a"),
+			"-d", `{"_id":"a","_attachments":{"foo.txt":{"sidecar":"../outside.txt","stub":true}}}`},
+		Err:    "Sidecar file '../outside.txt' is not below " + filepath.Join(tmpdir, "a"),
+		Status: chttp.ExitReadError,
+	})
+	tests.Add("missing sidecar", test.CmdTest{
+		Args: []string{"http://localhost/foo", "--" + flagAttachmentDir, tmpdir,
+			"-d", `{"_id":"a","_attachments":{"foo.txt":{"sidecar":"a/bar.txt","stub":true}}}`},
+		Err:    "open " + filepath.Join(tmpdir, "a", "bar.txt") + ": no such file or directory",
+		Status: chttp.ExitReadError,
+	})
+	tests.Run(t, test.ValidateCmdTest([]string{"restore"}))
+
+	s := newBulkServer(t, map[string]string{
+		"POST /foo/_bulk_docs": `[{"id":"a","rev":"1-x","ok":true}]`,
+	})
+	defer s.Close()
+	test.ValidateCmdTest([]string{"restore"})(t, test.CmdTest{
+		Args: []string{s.URL + "/foo", "--" + kouch.FlagNewEdits + "=false",
+			"--" + flagAttachmentDir, tmpdir, "-d", doc},
+		Stdout: `{"failed":0,"read":1,"written":1}` + "\n",
+	})
+	expected := []string{
+		`POST /foo/_bulk_docs {"docs":[{"_attachments":{"foo.txt":{"content_type":"text/plain","data":"VGVzdCBjb250ZW50Cg==","revpos":1}},"_id":"a","_rev":"1-x"}],"new_edits":false}` + "\n",
+	}
+	if d := diff.Interface(expected, s.requests); d != nil {
+		t.Error(d)
+	}
+}
@@ -0,0 +1,87 @@
+package restore
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+)
+
+// attachmentReader wraps a document source, replacing attachment stubs which
+// reference sidecar files, as written by 'kouch dump --attachment-mode=sidecar',
+// with the Base64-encoded file contents. Plain stubs are rejected when
+// preserveRevs is set, as CouchDB cannot resolve them against a new database.
+type attachmentReader struct {
+	util.DocSource
+	attDir       string
+	preserveRevs bool
+}
+
+var _ util.DocSource = &attachmentReader{}
+
+// Next returns the next document, with any sidecar attachments inlined.
+func (r *attachmentReader) Next() (json.RawMessage, error) {
+	doc, err := r.DocSource.Next()
+	if err != nil || !bytes.Contains(doc, []byte(`"_attachments"`)) {
+		return doc, err
+	}
+	return r.readSidecars(doc)
+}
+
+func (r *attachmentReader) readSidecars(doc json.RawMessage) (json.RawMessage, error) {
+	var parsed map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &parsed); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	rawAtts, ok := parsed["_attachments"]
+	if !ok {
+		return doc, nil
+	}
+	var docID string
+	_ = json.Unmarshal(parsed["_id"], &docID)
+	var atts map[string]map[string]interface{}
+	if err := json.Unmarshal(rawAtts, &atts); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	for filename, att := range atts {
+		sidecar, ok := att["sidecar"].(string)
+		if !ok {
+			if stub, _ := att["stub"].(bool); stub && r.preserveRevs {
+				return nil, errors.NewExitError(chttp.ExitReadError, "Document '%s' contains a stub for attachment '%s'; dump with --attachment-mode=inline or sidecar to preserve attachments", docID, filename)
+			}
+			continue
+		}
+		if r.attDir == "" {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Document '%s' references sidecar file '%s'; provide --%s", docID, sidecar, flagAttachmentDir)
+		}
+		content, err := r.readSidecar(sidecar)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range []string{"stub", "sidecar", "length", "digest"} {
+			delete(att, key)
+		}
+		att["data"] = base64.StdEncoding.EncodeToString(content)
+	}
+	var err error
+	if parsed["_attachments"], err = json.Marshal(atts); err != nil {
+		return nil, err
+	}
+	return json.Marshal(parsed)
+}
+
+// readSidecar reads the sidecar file at relPath, which must be below r.attDir.
+func (r *attachmentReader) readSidecar(relPath string) ([]byte, error) {
+	path := filepath.Join(r.attDir, filepath.FromSlash(relPath))
+	if rel, err := filepath.Rel(r.attDir, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, errors.NewExitError(chttp.ExitReadError, "Sidecar file '%s' is not below %s", relPath, r.attDir)
+	}
+	content, err := ioutil.ReadFile(path)
+	return content, errors.WrapExitError(chttp.ExitReadError, err)
+}
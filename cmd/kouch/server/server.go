@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/util"
+)
+
+func init() {
+	registry.Register([]string{"get"}, serverCmd)
+	registry.Register([]string{"get"}, upCmd)
+	registry.Register([]string{"get"}, membershipCmd)
+}
+
+func serverCmd() *cobra.Command {
+	return rootEndpointCmd(&cobra.Command{
+		Use:   "server [target]",
+		Short: "Returns meta information about the server",
+		Long: "Returns meta information about the CouchDB server, such as its version, vendor, and enabled features.\n\n" +
+			kouch.TargetHelpText(kouch.TargetRoot),
+	}, "/")
+}
+
+func upCmd() *cobra.Command {
+	return rootEndpointCmd(&cobra.Command{
+		Use:   "up [target]",
+		Short: "Confirms that the server is up, running, and ready to respond to requests",
+		Long: "Confirms that the server is up, running, and ready to respond to requests. " +
+			"If the server is in maintenance mode, a 404 status is returned.\n\n" +
+			kouch.TargetHelpText(kouch.TargetRoot),
+	}, "/_up")
+}
+
+func membershipCmd() *cobra.Command {
+	return rootEndpointCmd(&cobra.Command{
+		Use:   "membership [target]",
+		Short: "Returns the nodes of the cluster",
+		Long: "Returns the nodes that this node is connected to, as all_nodes, and the nodes configured as members of the cluster, as cluster_nodes.\n\n" +
+			kouch.TargetHelpText(kouch.TargetRoot),
+	}, "/_membership")
+}
+
+// rootEndpointCmd sets up cmd to fetch the server-level endpoint path.
+func rootEndpointCmd(cmd *cobra.Command, path string) *cobra.Command {
+	cmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		ctx := kouch.GetContext(cmd)
+		o, err := util.CommonOptions(ctx, kouch.TargetRoot, cmd.Flags())
+		if err != nil {
+			return err
+		}
+		return util.ChttpDo(ctx, http.MethodGet, path, o)
+	}
+	cmd.PersistentFlags().BoolP(kouch.FlagHead, kouch.FlagShortHead, false, "Fetch the headers only.")
+	return cmd
+}
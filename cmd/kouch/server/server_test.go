@@ -0,0 +1,75 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func serve(t *testing.T, tests *testy.Table, path, body string) string {
+	s := testy.ServeResponseValidator(t, &http.Response{
+		StatusCode: 200,
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+			"Date":         []string{"Mon, 20 Aug 2018 08:55:52 GMT"},
+		},
+		Body: ioutil.NopCloser(strings.NewReader(body)),
+	}, func(t *testing.T, r *http.Request) {
+		if r.URL.Path != path {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+	})
+	tests.Cleanup(s.Close)
+	return s.URL
+}
+
+func TestGetServerCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("positional root", func(t *testing.T) interface{} {
+		return test.CmdTest{
+			Args:   []string{serve(t, tests, "/", `{"couchdb":"Welcome","version":"2.3.0","vendor":{"name":"The Apache Software Foundation"},"features":["scheduler"]}`)},
+			Stdout: `{"couchdb":"Welcome","features":["scheduler"],"vendor":{"name":"The Apache Software Foundation"},"version":"2.3.0"}`,
+		}
+	})
+	tests.Add("head", func(t *testing.T) interface{} {
+		return test.CmdTest{
+			Args: []string{"--root", serve(t, tests, "/", `{"couchdb":"Welcome"}`), "-I"},
+			Stdout: "Content-Length: 21\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Date: Mon, 20 Aug 2018 08:55:52 GMT\r\n",
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "server"}))
+}
+
+func TestGetUpCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("up", func(t *testing.T) interface{} {
+		return test.CmdTest{
+			Args:   []string{"--root", serve(t, tests, "/_up", `{"status":"ok"}`)},
+			Stdout: `{"status":"ok"}`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "up"}))
+}
+
+func TestGetMembershipCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("membership", func(t *testing.T) interface{} {
+		return test.CmdTest{
+			Args:   []string{"--root", serve(t, tests, "/_membership", `{"all_nodes":["node1@127.0.0.1"],"cluster_nodes":["node1@127.0.0.1"]}`)},
+			Stdout: `{"all_nodes":["node1@127.0.0.1"],"cluster_nodes":["node1@127.0.0.1"]}`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "membership"}))
+}
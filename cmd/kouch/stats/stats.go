@@ -0,0 +1,171 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	kio "github.com/go-kivik/kouch/io"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagNode     = "node"
+	flagPath     = "path"
+	flagSystem   = "system"
+	flagWatch    = "watch"
+	flagInterval = "interval"
+)
+
+const (
+	defaultNode     = "_local"
+	defaultInterval = 15 * time.Second
+)
+
+func init() {
+	registry.Register([]string{"get"}, statsCmd)
+}
+
+func statsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats [target]",
+		Short: "Returns the statistics of a node",
+		Long: "Returns the statistics of a node, from /_node/{node}/_stats, or, with --" + flagSystem + ", the Erlang VM statistics, from /_node/{node}/_system.\n\n" +
+			"With --" + flagPath + ", only the statistics below the given path, such as `couchdb/request_time`, are returned. " +
+			"They are nested under the path, so that the output has the same structure as the full statistics.\n\n" +
+			"Use `--" + kouch.FlagOutputFormat + " prometheus` to output the statistics in the Prometheus text exposition format. " +
+			"With --" + flagWatch + ", the statistics are fetched repeatedly, and each snapshot is written in turn.\n\n" +
+			kouch.TargetHelpText(kouch.TargetRoot),
+		RunE: getStatsCmd,
+	}
+	f := cmd.Flags()
+	f.String(flagNode, defaultNode, "The node name. `"+defaultNode+"` is the node which handles the request.")
+	f.String(flagPath, "", "Return only the statistics below this path, such as `couchdb/request_time`.")
+	f.Bool(flagSystem, false, "Return the Erlang VM statistics.")
+	f.Bool(flagWatch, false, "Refresh the statistics until interrupted.")
+	f.Duration(flagInterval, defaultInterval, "How often to refresh the statistics, with --"+flagWatch+".")
+	return cmd
+}
+
+type stats struct {
+	o        *kouch.Options
+	node     string
+	path     []string
+	system   bool
+	watch    bool
+	interval time.Duration
+}
+
+func getStatsCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	s, err := statsOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	c, err := s.o.NewClient()
+	if err != nil {
+		return err
+	}
+	w, err := kio.NewDocWriter(ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		doc, err := s.fetch(ctx, c)
+		if err != nil {
+			return err
+		}
+		if err := w.WriteDoc(doc); err != nil {
+			return err
+		}
+		if !s.watch {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+func statsOpts(ctx context.Context, flags *pflag.FlagSet) (*stats, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetRoot, flags)
+	if err != nil {
+		return nil, err
+	}
+	s := &stats{o: o}
+	if s.node, err = flags.GetString(flagNode); err != nil {
+		return nil, err
+	}
+	if s.node == "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must not be empty", flagNode)
+	}
+	path, err := flags.GetString(flagPath)
+	if err != nil {
+		return nil, err
+	}
+	if path = strings.Trim(path, "/"); path != "" {
+		s.path = strings.Split(path, "/")
+	}
+	if s.system, err = flags.GetBool(flagSystem); err != nil {
+		return nil, err
+	}
+	if s.system && len(s.path) > 0 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s is not supported with --%s", flagPath, flagSystem)
+	}
+	if s.watch, err = flags.GetBool(flagWatch); err != nil {
+		return nil, err
+	}
+	if s.interval, err = flags.GetDuration(flagInterval); err != nil {
+		return nil, err
+	}
+	if s.interval <= 0 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must be positive", flagInterval)
+	}
+	return s, nil
+}
+
+// endpoint returns the path of the statistics endpoint.
+func (s *stats) endpoint() string {
+	path := "/_node/" + url.PathEscape(s.node)
+	if s.system {
+		return path + "/_system"
+	}
+	path += "/_stats"
+	for _, segment := range s.path {
+		path += "/" + url.PathEscape(segment)
+	}
+	return path
+}
+
+// fetch returns the statistics, nested under the path, if any.
+func (s *stats) fetch(ctx context.Context, c *chttp.Client) ([]byte, error) {
+	res, err := c.DoReq(ctx, http.MethodGet, s.endpoint(), s.o.Options)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close() // nolint: errcheck
+	if err = chttp.ResponseError(res); err != nil {
+		return nil, err
+	}
+	var result interface{}
+	dec := json.NewDecoder(res.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&result); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitWeirdReply, err)
+	}
+	for i := len(s.path) - 1; i >= 0; i-- {
+		result = map[string]interface{}{s.path[i]: result}
+	}
+	return json.Marshal(result)
+}
@@ -0,0 +1,119 @@
+package stats
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+// shutdown is served once the expected responses are exhausted, to end a
+// watch.
+var shutdown = test.Response{Status: http.StatusInternalServerError, Body: `{"error":"unknown_error","reason":"shutting down"}`}
+
+type statsTest struct {
+	test.CmdTest
+	requests func() []string
+	expected []string
+}
+
+func TestGetStatsCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("path with system", statsTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost", "--" + flagSystem, "--" + flagPath, "couchdb"},
+			Err:    "--path is not supported with --system",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("invalid interval", statsTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost", "--" + flagInterval, "-1s"},
+			Err:    "--interval must be positive",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("stats", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Body: `{"couchdb":{"open_databases":{"value":3,"type":"counter","desc":"number of open databases"}}}`})
+		tests.Cleanup(s.Close)
+		return statsTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL},
+				Stdout: `{"couchdb":{"open_databases":{"desc":"number of open databases","type":"counter","value":3}}}`,
+			},
+			requests: s.Requests,
+			expected: []string{"GET /_node/_local/_stats "},
+		}
+	})
+	tests.Add("path", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Body: `{"value":3,"type":"counter","desc":"number of open databases"}`})
+		tests.Cleanup(s.Close)
+		return statsTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL, "--" + flagNode, "couchdb@127.0.0.1", "--" + flagPath, "/couchdb/open_databases/"},
+				Stdout: `{"couchdb":{"open_databases":{"desc":"number of open databases","type":"counter","value":3}}}`,
+			},
+			requests: s.Requests,
+			expected: []string{"GET /_node/couchdb@127.0.0.1/_stats/couchdb/open_databases "},
+		}
+	})
+	tests.Add("system prometheus", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Body: `{"uptime":259,"memory":{"processes":1234}}`})
+		tests.Cleanup(s.Close)
+		return statsTest{
+			CmdTest: test.CmdTest{
+				Args: []string{s.URL, "--" + flagSystem, "-F", "prometheus", "--prometheus-prefix", "couchdb_erlang"},
+				Stdout: `# TYPE couchdb_erlang_memory_processes untyped
+couchdb_erlang_memory_processes 1234
+# TYPE couchdb_erlang_uptime untyped
+couchdb_erlang_uptime 259
+`,
+			},
+			requests: s.Requests,
+			expected: []string{"GET /_node/_local/_system "},
+		}
+	})
+	tests.Add("watch prometheus", func(t *testing.T) interface{} {
+		s := test.NewServer(t,
+			test.Response{Body: `{"value":3,"type":"gauge","desc":"number of open databases"}`},
+			test.Response{Body: `{"value":4,"type":"gauge","desc":"number of open databases"}`},
+			shutdown,
+		)
+		tests.Cleanup(s.Close)
+		return statsTest{
+			CmdTest: test.CmdTest{
+				Args: []string{s.URL, "--" + flagPath, "couchdb/open_databases", "--" + flagWatch, "--" + flagInterval, "1ms", "-F", "prometheus"},
+				Stdout: `# HELP couchdb_open_databases number of open databases
+# TYPE couchdb_open_databases gauge
+couchdb_open_databases 3
+# HELP couchdb_open_databases number of open databases
+# TYPE couchdb_open_databases gauge
+couchdb_open_databases 4
+`,
+				Err:    "Internal Server Error: shutting down",
+				Status: chttp.ExitNotRetrieved,
+			},
+			requests: s.Requests,
+			expected: []string{
+				"GET /_node/_local/_stats/couchdb/open_databases ",
+				"GET /_node/_local/_stats/couchdb/open_databases ",
+				"GET /_node/_local/_stats/couchdb/open_databases ",
+			},
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt statsTest) {
+		test.ValidateCmdTest([]string{"get", "stats"})(t, tt.CmdTest)
+		if tt.requests != nil {
+			if d := diff.Interface(tt.expected, tt.requests()); d != nil {
+				t.Errorf("Unexpected requests:\n%s", d)
+			}
+		}
+	})
+}
@@ -0,0 +1,280 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	kio "github.com/go-kivik/kouch/io"
+	"github.com/go-kivik/kouch/kouchio"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagType     = "type"
+	flagWatch    = "watch"
+	flagInterval = "interval"
+)
+
+const defaultInterval = 2 * time.Second
+
+// taskTypes are the task types which may be selected with --type.
+var taskTypes = []string{"indexer", "replication", "database_compaction", "view_compaction"}
+
+// clearScreen moves the cursor to the top left corner of the terminal, and
+// clears it.
+const clearScreen = "\x1b[H\x1b[2J"
+
+// isTTY is a variable, to facilitate testing.
+var isTTY = util.IsTerminalWriter
+
+func init() {
+	registry.Register([]string{"get"}, activeTasksCmd)
+}
+
+func activeTasksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "active-tasks [target]",
+		Short: "Returns the tasks running on the server",
+		Long: "Returns the tasks running on the server, such as indexing, replication and compaction.\n\n" +
+			"With --" + flagWatch + ", the tasks are fetched repeatedly. On a terminal, a table of the tasks is refreshed in place; " +
+			"otherwise, each snapshot is written as a separate document, in the selected output format.\n\n" +
+			kouch.TargetHelpText(kouch.TargetRoot),
+		RunE: getActiveTasksCmd,
+	}
+	f := cmd.Flags()
+	f.StringSlice(flagType, nil, "Return only tasks of the specified types: `"+strings.Join(taskTypes, "`, `")+"`.")
+	f.Bool(flagWatch, false, "Refresh the tasks until interrupted.")
+	f.Duration(flagInterval, defaultInterval, "How often to refresh the tasks, with --"+flagWatch+".")
+	return cmd
+}
+
+type activeTasks struct {
+	o        *kouch.Options
+	types    map[string]bool
+	watch    bool
+	interval time.Duration
+}
+
+func getActiveTasksCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	a, err := activeTasksOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	c, err := a.o.NewClient()
+	if err != nil {
+		return err
+	}
+	if a.watch {
+		return a.watchTasks(ctx, c)
+	}
+	tasks, err := a.fetch(ctx, c)
+	if err != nil {
+		return err
+	}
+	out := kouch.Output(ctx)
+	if err := json.NewEncoder(out).Encode(tasks); err != nil {
+		return err
+	}
+	return kouchio.CloseWriter(out)
+}
+
+func activeTasksOpts(ctx context.Context, flags *pflag.FlagSet) (*activeTasks, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetRoot, flags)
+	if err != nil {
+		return nil, err
+	}
+	a := &activeTasks{o: o}
+	types, err := flags.GetStringSlice(flagType)
+	if err != nil {
+		return nil, err
+	}
+	if len(types) > 0 {
+		a.types = make(map[string]bool, len(types))
+	}
+	for _, t := range types {
+		if !validType(t) {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid task type '%s'. Supported options: `%s`", t, strings.Join(taskTypes, "`, `"))
+		}
+		a.types[t] = true
+	}
+	if a.watch, err = flags.GetBool(flagWatch); err != nil {
+		return nil, err
+	}
+	if a.interval, err = flags.GetDuration(flagInterval); err != nil {
+		return nil, err
+	}
+	if a.interval <= 0 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must be positive", flagInterval)
+	}
+	return a, nil
+}
+
+func validType(t string) bool {
+	for _, valid := range taskTypes {
+		if t == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch returns the active tasks, filtered by type.
+func (a *activeTasks) fetch(ctx context.Context, c *chttp.Client) ([]map[string]interface{}, error) {
+	res, err := c.DoReq(ctx, http.MethodGet, "/_active_tasks", a.o.Options)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close() // nolint: errcheck
+	if err = chttp.ResponseError(res); err != nil {
+		return nil, err
+	}
+	var tasks []map[string]interface{}
+	dec := json.NewDecoder(res.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&tasks); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitWeirdReply, err)
+	}
+	filtered := make([]map[string]interface{}, 0, len(tasks))
+	for _, task := range tasks {
+		if t, _ := task["type"].(string); a.types == nil || a.types[t] {
+			filtered = append(filtered, task)
+		}
+	}
+	return filtered, nil
+}
+
+// watchTasks fetches the tasks every interval, until ctx is cancelled or an
+// error occurs.
+func (a *activeTasks) watchTasks(ctx context.Context, c *chttp.Client) error {
+	write, err := snapshotWriter(ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		tasks, err := a.fetch(ctx, c)
+		if err != nil {
+			return err
+		}
+		if err := write(tasks); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(a.interval):
+		}
+	}
+}
+
+// snapshotWriter returns a function which writes each snapshot of the tasks.
+// On a terminal, the table of tasks is redrawn. Otherwise, each snapshot is
+// written as a separate document, in the selected output format.
+func snapshotWriter(ctx context.Context) (func([]map[string]interface{}) error, error) {
+	if out := kouchio.Underlying(kouch.Output(ctx)); isTTY(out) {
+		return func(tasks []map[string]interface{}) error {
+			if err := writeScreen(out, tasks); err != nil {
+				return errors.WrapExitError(chttp.ExitWriteError, err)
+			}
+			return nil
+		}, nil
+	}
+	w, err := kio.NewDocWriter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func(tasks []map[string]interface{}) error {
+		doc, err := json.Marshal(tasks)
+		if err != nil {
+			return err
+		}
+		return w.WriteDoc(doc)
+	}, nil
+}
+
+// writeScreen clears the terminal, and writes a table of tasks.
+func writeScreen(w io.Writer, tasks []map[string]interface{}) error {
+	buf := &bytes.Buffer{}
+	buf.WriteString(clearScreen)
+	if err := writeTable(buf, tasks); err != nil {
+		return err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func writeTable(w io.Writer, tasks []map[string]interface{}) error {
+	rows := make([][]string, len(tasks))
+	for i, task := range tasks {
+		rows[i] = []string{
+			field(task, "type"),
+			taskDatabase(task),
+			field(task, "design_document"),
+			progress(task),
+			changes(task),
+			startedOn(task),
+		}
+	}
+	return util.WriteTable(w, []string{"TYPE", "DATABASE", "DESIGN DOC", "PROGRESS", "CHANGES", "STARTED"}, rows)
+}
+
+func field(task map[string]interface{}, name string) string {
+	if v, ok := task[name]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// taskDatabase returns the database of a task, or the source and target of a
+// replication.
+func taskDatabase(task map[string]interface{}) string {
+	if db := field(task, "database"); db != "" {
+		return util.TaskDatabase(db)
+	}
+	if source, target := field(task, "source"), field(task, "target"); source != "" || target != "" {
+		return source + " -> " + target
+	}
+	return ""
+}
+
+func progress(task map[string]interface{}) string {
+	if p := field(task, "progress"); p != "" {
+		return p + "%"
+	}
+	return ""
+}
+
+func changes(task map[string]interface{}) string {
+	done, total := field(task, "changes_done"), field(task, "total_changes")
+	if total == "" {
+		return done
+	}
+	if done == "" {
+		done = "0"
+	}
+	return done + "/" + total
+}
+
+func startedOn(task map[string]interface{}) string {
+	n, ok := task["started_on"].(json.Number)
+	if !ok {
+		return ""
+	}
+	secs, err := n.Int64()
+	if err != nil {
+		return n.String()
+	}
+	return time.Unix(secs, 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,178 @@
+package tasks
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+	"github.com/go-kivik/kouch/internal/util"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+const tasksJSON = `[
+	{"type":"indexer","database":"shards/00000000-7fffffff/foo.1530000000","design_document":"_design/app","progress":40,"changes_done":400,"total_changes":1000,"started_on":1530000000},
+	{"type":"replication","source":"http://localhost:5984/foo/","target":"http://localhost:5984/bar/","docs_written":12,"started_on":1530000100},
+	{"type":"database_compaction","database":"bar","progress":90,"changes_done":90,"total_changes":100,"started_on":1530000200}
+]`
+
+// shutdown is served once the expected responses are exhausted, to end a
+// watch.
+var shutdown = test.Response{Status: http.StatusInternalServerError, Body: `{"error":"unknown_error","reason":"shutting down"}`}
+
+type tasksTest struct {
+	test.CmdTest
+	requests func() []string
+	expected []string
+}
+
+func validateTasksTest(t *testing.T, tt tasksTest) {
+	test.ValidateCmdTest([]string{"get", "active-tasks"})(t, tt.CmdTest)
+	if tt.requests != nil {
+		if d := diff.Interface(tt.expected, tt.requests()); d != nil {
+			t.Errorf("Unexpected requests:\n%s", d)
+		}
+	}
+}
+
+func TestGetActiveTasksCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("invalid type", tasksTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost", "--" + flagType, "indexer,reindexer"},
+			Err:    "Invalid task type 'reindexer'. Supported options: `indexer`, `replication`, `database_compaction`, `view_compaction`",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("invalid interval", tasksTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost", "--" + flagInterval, "0s"},
+			Err:    "--interval must be positive",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("all", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Body: `[{"type":"indexer","progress":40}]`})
+		tests.Cleanup(s.Close)
+		return tasksTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL},
+				Stdout: `[{"progress":40,"type":"indexer"}]`,
+			},
+			requests: s.Requests,
+			expected: []string{"GET /_active_tasks "},
+		}
+	})
+	tests.Add("filtered", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Body: tasksJSON})
+		tests.Cleanup(s.Close)
+		return tasksTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL, "--" + flagType, "replication", "--" + flagType, "database_compaction"},
+				Stdout: `[{"docs_written":12,"source":"http://localhost:5984/foo/","started_on":1530000100,"target":"http://localhost:5984/bar/","type":"replication"},{"changes_done":90,"database":"bar","progress":90,"started_on":1530000200,"total_changes":100,"type":"database_compaction"}]`,
+			},
+			requests: s.Requests,
+			expected: []string{"GET /_active_tasks "},
+		}
+	})
+	tests.Add("none matching", func(t *testing.T) interface{} {
+		s := test.NewServer(t, test.Response{Body: tasksJSON})
+		tests.Cleanup(s.Close)
+		return tasksTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL, "--" + flagType, "view_compaction"},
+				Stdout: `[]`,
+			},
+			requests: s.Requests,
+			expected: []string{"GET /_active_tasks "},
+		}
+	})
+	tests.Add("watch json lines", func(t *testing.T) interface{} {
+		s := test.NewServer(t,
+			test.Response{Body: `[{"type":"indexer","progress":40}]`},
+			test.Response{Body: `[{"type":"replication"},{"type":"indexer","progress":80}]`},
+			shutdown,
+		)
+		tests.Cleanup(s.Close)
+		return tasksTest{
+			CmdTest: test.CmdTest{
+				Args: []string{s.URL, "--" + flagWatch, "--" + flagInterval, "1ms", "--" + flagType, "indexer"},
+				Stdout: `[{"progress":40,"type":"indexer"}]
+[{"progress":80,"type":"indexer"}]
+`,
+				Err:    "Internal Server Error: shutting down",
+				Status: chttp.ExitNotRetrieved,
+			},
+			requests: s.Requests,
+			expected: []string{
+				"GET /_active_tasks ",
+				"GET /_active_tasks ",
+				"GET /_active_tasks ",
+			},
+		}
+	})
+
+	tests.Add("watch yaml", func(t *testing.T) interface{} {
+		s := test.NewServer(t,
+			test.Response{Body: `[{"type":"indexer","progress":40}]`},
+			test.Response{Body: `[{"type":"indexer","progress":80}]`},
+			shutdown,
+		)
+		tests.Cleanup(s.Close)
+		return tasksTest{
+			CmdTest: test.CmdTest{
+				Args: []string{s.URL, "--" + flagWatch, "--" + flagInterval, "1ms", "-F", "yaml"},
+				Stdout: `- progress: 40
+  type: indexer
+---
+- progress: 80
+  type: indexer
+`,
+				Err:    "Internal Server Error: shutting down",
+				Status: chttp.ExitNotRetrieved,
+			},
+			requests: s.Requests,
+			expected: []string{
+				"GET /_active_tasks ",
+				"GET /_active_tasks ",
+				"GET /_active_tasks ",
+			},
+		}
+	})
+
+	tests.Run(t, validateTasksTest)
+}
+
+func TestWatchTable(t *testing.T) {
+	isTTY = func(io.Writer) bool { return true }
+	defer func() { isTTY = util.IsTerminalWriter }()
+	tests := testy.NewTable()
+	tests.Add("table", func(t *testing.T) interface{} {
+		s := test.NewServer(t,
+			test.Response{Body: tasksJSON},
+			shutdown,
+		)
+		tests.Cleanup(s.Close)
+		return tasksTest{
+			CmdTest: test.CmdTest{
+				Args: []string{s.URL, "--" + flagWatch, "--" + flagInterval, "1ms"},
+				Stdout: clearScreen +
+					"TYPE                 DATABASE                                                  DESIGN DOC   PROGRESS  CHANGES   STARTED\n" +
+					"indexer              foo                                                       _design/app  40%       400/1000  2018-06-26T08:00:00Z\n" +
+					"replication          http://localhost:5984/foo/ -> http://localhost:5984/bar/                                   2018-06-26T08:01:40Z\n" +
+					"database_compaction  bar                                                                    90%       90/100    2018-06-26T08:03:20Z\n",
+				Err:    "Internal Server Error: shutting down",
+				Status: chttp.ExitNotRetrieved,
+			},
+			requests: s.Requests,
+			expected: []string{"GET /_active_tasks ", "GET /_active_tasks "},
+		}
+	})
+
+	tests.Run(t, validateTasksTest)
+}
@@ -0,0 +1,19 @@
+// Package tester provides the 'test' command. It is not named test, to avoid
+// confusion with the package which registers commands for tests.
+package tester
+
+import (
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registry.Register(nil, testCmd)
+}
+
+func testCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "test",
+		Short: "Test a resource locally, without a server.",
+	}
+}
@@ -0,0 +1,111 @@
+package views
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/alldocs"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getViewCmd)
+}
+
+func getViewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "view [target]",
+		Short: "Queries a view.",
+		Long: "Queries a view, subject to possible restrictions.\n\n" +
+			"When --" + kouch.FlagKeys + " is given, the keys are sent in the request body, with POST.\n\n" +
+			kouch.TargetHelpText(kouch.TargetView),
+		RunE: getViewResultsCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}/{ddoc}/{view}.")
+	f.String(kouch.FlagDesignDoc, "", "The design document. May be provided with the target in the format /{db}/{ddoc}/{view}.")
+	f.String(kouch.FlagView, "", "The view name. May be provided with the target in the format /{db}/{ddoc}/{view}.")
+	alldocs.AddQueryFlags(f)
+	return cmd
+}
+
+func getViewResultsCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := getViewOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	return getView(ctx, o)
+}
+
+func getViewOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetView, flags)
+	if err != nil {
+		return nil, err
+	}
+	if e := alldocs.SetQueryParams(o, flags); e != nil {
+		return nil, e
+	}
+	setKeysBody(o)
+	return o, nil
+}
+
+// setKeysBody moves any keys from the query parameters to the request body,
+// and returns true if it did so.
+func setKeysBody(o *kouch.Options) bool {
+	keys, ok := o.Options.Query["keys"]
+	if !ok {
+		return false
+	}
+	o.Options.Query.Del("keys")
+	o.Body = chttp.EncodeBody(map[string]interface{}{"keys": parseKeys(keys)})
+	return true
+}
+
+// parseKeys converts keys, in JSON format, to an array suitable for the
+// request body. Keys which are not valid JSON are treated as strings.
+func parseKeys(keys []string) []interface{} {
+	result := make([]interface{}, len(keys))
+	for i, key := range keys {
+		var k interface{}
+		if err := json.Unmarshal([]byte(key), &k); err != nil {
+			k = key
+		}
+		result[i] = k
+	}
+	return result
+}
+
+func getView(ctx context.Context, o *kouch.Options) error {
+	if err := validateTarget(o.Target); err != nil {
+		return err
+	}
+	method := http.MethodGet
+	if o.Body != nil {
+		method = http.MethodPost
+	}
+	return util.ChttpDo(ctx, method, util.ViewPath(o), o)
+}
+
+func validateTarget(t *kouch.Target) error {
+	if t.View == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No view name provided")
+	}
+	if t.DesignDoc == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No design document provided")
+	}
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package views
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/put"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestGetViewOpts(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("defaults", test.OptionsTest{
+		Expected: &kouch.Options{
+			Target:  &kouch.Target{},
+			Options: &chttp.Options{},
+		},
+	})
+	tests.Add("short target", test.OptionsTest{
+		Args: []string{"foo/bar/baz"},
+		Expected: &kouch.Options{
+			Target:  &kouch.Target{Database: "foo", DesignDoc: "bar", View: "baz"},
+			Options: &chttp.Options{},
+		},
+	})
+	tests.Add("flags", test.OptionsTest{
+		Args: []string{"--" + kouch.FlagDatabase, "foo", "--" + kouch.FlagDesignDoc, "_design/bar", "--" + kouch.FlagView, "baz"},
+		Expected: &kouch.Options{
+			Target:  &kouch.Target{Database: "foo", DesignDoc: "bar", View: "baz"},
+			Options: &chttp.Options{},
+		},
+	})
+	tests.Add("duplicate view", test.OptionsTest{
+		Args:   []string{"foo/bar/baz", "--" + kouch.FlagView, "qux"},
+		Err:    "Must not use --view and pass view as part of the target",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("query params", test.OptionsTest{
+		Args: []string{"foo/_design/bar/_view/baz", "--" + kouch.FlagStartKey, `"a"`, "--" + kouch.FlagGroupLevel, "2",
+			"--" + kouch.FlagReduce + "=false", "--" + kouch.FlagStale, "ok", "--" + kouch.FlagIncludeDocs},
+		Expected: &kouch.Options{
+			Target: &kouch.Target{Database: "foo", DesignDoc: "bar", View: "baz"},
+			Options: &chttp.Options{
+				Query: url.Values{
+					"startkey":     []string{`"a"`},
+					"group_level":  []string{"2"},
+					"reduce":       []string{"false"},
+					"stale":        []string{"ok"},
+					"include_docs": []string{"true"},
+				},
+			},
+		},
+	})
+
+	tests.Run(t, test.Options(getViewCmd, getViewOpts))
+}
+
+func TestGetViewCmd(t *testing.T) {
+	serve := func(t *testing.T, method, path, reqBody string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != method || r.URL.RequestURI() != path {
+				t.Errorf("Unexpected request: %s %s", r.Method, r.URL.RequestURI())
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			if string(body) != reqBody {
+				t.Errorf("Unexpected request body: %s", string(body))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"total_rows":0,"offset":0,"rows":[]}`))
+		}))
+	}
+	tests := testy.NewTable()
+	tests.Add("no view", test.CmdTest{
+		Args:   []string{"--" + kouch.FlagDatabase, "foo"},
+		Err:    "No view name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("no database", test.CmdTest{
+		Args:   []string{"bar/baz"},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("get", func(t *testing.T) interface{} {
+		s := serve(t, "GET", "/foo/_design/bar/_view/baz?limit=10", "")
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar/baz", "--" + kouch.FlagLimit, "10"},
+			Stdout: `{"offset":0,"rows":[],"total_rows":0}`,
+		}
+	})
+	tests.Add("partitioned", func(t *testing.T) interface{} {
+		s := serve(t, "GET", "/foo/_partition/p/_design/bar/_view/baz", "")
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/_design/bar/_view/baz", "--" + kouch.FlagPartition, "p"},
+			Stdout: `{"offset":0,"rows":[],"total_rows":0}`,
+		}
+	})
+	tests.Add("keys", func(t *testing.T) interface{} {
+		s := serve(t, "POST", "/foo/_design/bar/_view/baz?include_docs=true", `{"keys":["a",1,["x","y"],"plain"]}`+"\n")
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo/bar/baz", "--" + kouch.FlagIncludeDocs, "--" + kouch.FlagKeys, `"a"`,
+				"--" + kouch.FlagKeys, "1", "--" + kouch.FlagKeys, `["x","y"]`, "--" + kouch.FlagKeys, "plain"},
+			Stdout: `{"offset":0,"rows":[],"total_rows":0}`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "view"}))
+}
@@ -0,0 +1,57 @@
+package views
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getDesignInfoCmd)
+}
+
+func getDesignInfoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "design-info [target]",
+		Aliases: []string{"ddoc-info"},
+		Short:   "Fetches information about a design document's index.",
+		Long: "Fetches information about the index of a design document, such as its size, signature, and whether it is being updated.\n\n" +
+			"The design document may be given with or without the _design/ prefix.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDocument),
+		RunE: getDesignDocInfoCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}/_design/{ddoc}.")
+	f.String(kouch.FlagDesignDoc, "", "The design document. May be provided with the target in the format /{db}/_design/{ddoc}.")
+	return cmd
+}
+
+func getDesignDocInfoCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := getDesignInfoOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if err := validateDesignTarget(o.Target, false); err != nil {
+		return err
+	}
+	return util.ChttpDo(ctx, http.MethodGet, util.DesignDocPath(o)+"/_info", o)
+}
+
+func getDesignInfoOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDocument, flags)
+	if err != nil {
+		return nil, err
+	}
+	if o.DesignDoc == "" {
+		o.DesignDoc = strings.TrimPrefix(o.Document, "_design/")
+	}
+	o.Document = ""
+	return o, nil
+}
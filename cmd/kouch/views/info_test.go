@@ -0,0 +1,64 @@
+package views
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func TestGetDesignInfoOpts(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("long target", test.OptionsTest{
+		Args: []string{"foo/_design/bar"},
+		Expected: &kouch.Options{
+			Target:  &kouch.Target{Database: "foo", DesignDoc: "bar"},
+			Options: &chttp.Options{},
+		},
+	})
+	tests.Add("short target", test.OptionsTest{
+		Args: []string{"foo/bar"},
+		Expected: &kouch.Options{
+			Target:  &kouch.Target{Database: "foo", DesignDoc: "bar"},
+			Options: &chttp.Options{},
+		},
+	})
+	tests.Add("flags", test.OptionsTest{
+		Args: []string{"--" + kouch.FlagDatabase, "foo", "--" + kouch.FlagDesignDoc, "_design/bar"},
+		Expected: &kouch.Options{
+			Target:  &kouch.Target{Database: "foo", DesignDoc: "bar"},
+			Options: &chttp.Options{},
+		},
+	})
+
+	tests.Run(t, test.Options(getDesignInfoCmd, getDesignInfoOpts))
+}
+
+func TestGetDesignInfoCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("no design doc", test.CmdTest{
+		Args:   []string{"--" + kouch.FlagDatabase, "foo"},
+		Err:    "No design document provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || r.URL.RequestURI() != "/foo/_design/bar/_info" {
+				t.Errorf("Unexpected request: %s %s", r.Method, r.URL.RequestURI())
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name":"bar","view_index":{"updater_running":false,"sizes":{"file":4120}}}`))
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/_design/bar"},
+			Stdout: `{"name":"bar","view_index":{"sizes":{"file":4120},"updater_running":false}}`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "design-info"}))
+}
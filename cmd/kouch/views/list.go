@@ -0,0 +1,57 @@
+package views
+
+import (
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/alldocs"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getListCmd)
+}
+
+func getListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list [target]",
+		Short: "Calls a list function.",
+		Long: "Calls a list function, applied to the results of a view.\n\n" +
+			"The view query options are the same as for 'kouch get view'. The response is output as received, without formatting.\n\n" +
+			kouch.TargetHelpText(kouch.TargetList),
+		RunE: getListResultCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}/{ddoc}/{func}/{view}.")
+	f.String(kouch.FlagDesignDoc, "", "The design document. May be provided with the target in the format /{db}/{ddoc}/{func}/{view}.")
+	f.String(kouch.FlagView, "", "The view name, optionally prefixed by another design document, as `ddoc/view`. May be provided with the target in the format /{db}/{ddoc}/{func}/{view}.")
+	alldocs.AddQueryFlags(f)
+	addParamFlag(f)
+	return cmd
+}
+
+func getListResultCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := designFuncOpts(ctx, kouch.TargetList, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if e := alldocs.SetQueryParams(o, cmd.Flags()); e != nil {
+		return e
+	}
+	if err := validateDesignTarget(o.Target, true); err != nil {
+		return err
+	}
+	if o.View == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No view name provided")
+	}
+	method := http.MethodGet
+	if setKeysBody(o) {
+		method = http.MethodPost
+	}
+	return getRaw(ctx, method, util.DesignFuncPath(o, "_list"), o)
+}
@@ -0,0 +1,42 @@
+package views
+
+import (
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func TestGetListCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("no function", test.CmdTest{
+		Args:   []string{"--" + kouch.FlagDatabase, "foo", "--" + kouch.FlagDesignDoc, "bar"},
+		Err:    "No function name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("incomplete target", test.CmdTest{
+		Args:   []string{"foo/bar/baz"},
+		Err:    "incomplete target URL",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("same design doc", func(t *testing.T) interface{} {
+		s := rawServer(t, "GET", "/foo/_design/bar/_list/baz/qux?limit=5", "")
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar/baz/qux", "--" + kouch.FlagLimit, "5"},
+			Stdout: "<h1>Hello</h1>\n",
+		}
+	})
+	tests.Add("other design doc, keys", func(t *testing.T) interface{} {
+		s := rawServer(t, "POST", "/foo/_design/bar/_list/baz/other/qux", `{"keys":["a"]}`+"\n")
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/_design/bar/_list/baz/other/qux", "--" + kouch.FlagKeys, "a"},
+			Stdout: "<h1>Hello</h1>\n",
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "list"}))
+}
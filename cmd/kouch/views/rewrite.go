@@ -0,0 +1,42 @@
+package views
+
+import (
+	"net/http"
+
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getRewriteCmd)
+}
+
+func getRewriteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rewrite [target]",
+		Short: "Fetches a path handled by rewrite rules.",
+		Long: "Fetches a path handled by the rewrite rules of a design document.\n\n" +
+			"The response is output as received, without formatting.\n\n" +
+			kouch.TargetHelpText(kouch.TargetRewrite),
+		RunE: getRewriteResultCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}/{ddoc}/{path}.")
+	f.String(kouch.FlagDesignDoc, "", "The design document. May be provided with the target in the format /{db}/{ddoc}/{path}.")
+	addParamFlag(f)
+	return cmd
+}
+
+func getRewriteResultCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := designFuncOpts(ctx, kouch.TargetRewrite, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if err := validateDesignTarget(o.Target, false); err != nil {
+		return err
+	}
+	return getRaw(ctx, http.MethodGet, util.DesignFuncPath(o, "_rewrite"), o)
+}
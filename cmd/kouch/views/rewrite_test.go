@@ -0,0 +1,36 @@
+package views
+
+import (
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func TestGetRewriteCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("no database", test.CmdTest{
+		Args:   []string{"bar/baz"},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("path", func(t *testing.T) interface{} {
+		s := rawServer(t, "GET", "/foo/_design/bar/_rewrite/some/path?q=x", "")
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar/some/path", "--" + flagParam, "q=x"},
+			Stdout: "<h1>Hello</h1>\n",
+		}
+	})
+	tests.Add("root", func(t *testing.T) interface{} {
+		s := rawServer(t, "GET", "/foo/_design/bar/_rewrite/", "")
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/_design/bar/_rewrite/"},
+			Stdout: "<h1>Hello</h1>\n",
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "rewrite"}))
+}
@@ -0,0 +1,103 @@
+package views
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/go-kivik/kouch/kouchio"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const flagParam = "param"
+
+func init() {
+	registry.Register([]string{"get"}, getShowCmd)
+}
+
+func getShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show [target]",
+		Short: "Calls a show function.",
+		Long: "Calls a show function, optionally applied to a document.\n\n" +
+			"The response is output as received, without formatting.\n\n" +
+			kouch.TargetHelpText(kouch.TargetShow),
+		RunE: getShowResultCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}/{ddoc}/{func}.")
+	f.String(kouch.FlagDesignDoc, "", "The design document. May be provided with the target in the format /{db}/{ddoc}/{func}.")
+	f.String(kouch.FlagDocument, "", "The document ID. May be provided with the target in the format /{db}/{ddoc}/{func}/{id}.")
+	addParamFlag(f)
+	return cmd
+}
+
+func getShowResultCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := designFuncOpts(ctx, kouch.TargetShow, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if err := validateDesignTarget(o.Target, true); err != nil {
+		return err
+	}
+	return getRaw(ctx, http.MethodGet, util.DesignFuncPath(o, "_show"), o)
+}
+
+// addParamFlag adds the --param flag, used to pass arbitrary query parameters
+// to design functions.
+func addParamFlag(f *pflag.FlagSet) {
+	f.StringArray(flagParam, nil, "A query parameter to pass to the function, in the format `key=value`. May be repeated.")
+}
+
+// designFuncOpts returns the options for a design function, with any --param
+// query parameters set.
+func designFuncOpts(ctx context.Context, scope kouch.TargetScope, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, scope, flags)
+	if err != nil {
+		return nil, err
+	}
+	params, err := flags.GetStringArray(flagParam)
+	if err != nil {
+		return nil, err
+	}
+	for _, param := range params {
+		parts := strings.SplitN(param, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid value for --%s. Expected format: `key=value`", flagParam)
+		}
+		o.Query().Add(parts[0], parts[1])
+	}
+	// Design functions may produce any content type.
+	o.Accept = "*/*"
+	return o, nil
+}
+
+// getRaw performs the request, writing the response body to the output as
+// received, without any formatting.
+func getRaw(ctx context.Context, method, path string, o *kouch.Options) error {
+	ctx = kouch.SetOutput(ctx, kouchio.Underlying(kouch.Output(ctx)))
+	return util.ChttpDo(ctx, method, path, o)
+}
+
+func validateDesignTarget(t *kouch.Target, needFunc bool) error {
+	if needFunc && t.Function == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No function name provided")
+	}
+	if t.DesignDoc == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No design document provided")
+	}
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package views
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+// rawServer returns a server which expects a single request, and responds
+// with a plain-text body.
+func rawServer(t *testing.T, method, path, reqBody string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method || r.URL.RequestURI() != path {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.RequestURI())
+		}
+		if accept := r.Header.Get("Accept"); accept != "*/*" {
+			t.Errorf("Unexpected Accept header: %s", accept)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != reqBody {
+			t.Errorf("Unexpected request body: %s", string(body))
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<h1>Hello</h1>\n"))
+	}))
+}
+
+func TestGetShowCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("no function", test.CmdTest{
+		Args:   []string{"--" + kouch.FlagDatabase, "foo"},
+		Err:    "No function name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid param", test.CmdTest{
+		Args:   []string{"foo/bar/baz", "--" + flagParam, "oink"},
+		Err:    "Invalid value for --param. Expected format: `key=value`",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("without document", func(t *testing.T) interface{} {
+		s := rawServer(t, "GET", "/foo/_design/bar/_show/baz?format=html", "")
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar/baz", "--" + flagParam, "format=html"},
+			Stdout: "<h1>Hello</h1>\n",
+		}
+	})
+	tests.Add("with document", func(t *testing.T) interface{} {
+		s := rawServer(t, "GET", "/foo/_design/bar/_show/baz/_design/qux", "")
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/_design/bar/_show/baz/_design/qux"},
+			Stdout: "<h1>Hello</h1>\n",
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "show"}))
+}
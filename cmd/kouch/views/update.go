@@ -0,0 +1,60 @@
+package views
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+)
+
+const flagContentType = "content-type"
+
+func init() {
+	registry.Register([]string{"put"}, putUpdateCmd)
+}
+
+func putUpdateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update [target]",
+		Short: "Calls an update function.",
+		Long: "Calls an update function, optionally applied to an existing document.\n\n" +
+			"The request body is read from the input. Without a document ID, the request is sent with POST, otherwise with PUT. " +
+			"The response is output as received, without formatting.\n\n" +
+			kouch.TargetHelpText(kouch.TargetUpdate),
+		RunE: putUpdateFuncCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}/{ddoc}/{func}.")
+	f.String(kouch.FlagDesignDoc, "", "The design document. May be provided with the target in the format /{db}/{ddoc}/{func}.")
+	f.String(kouch.FlagDocument, "", "The document ID. May be provided with the target in the format /{db}/{ddoc}/{func}/{id}.")
+	f.String(flagContentType, "", "The request body MIME type. Defaults to 'application/json'.")
+	addParamFlag(f)
+	f.BoolP(kouch.FlagYes, kouch.FlagShortYes, false, "Do not prompt for confirmation when using a protected context.")
+	return cmd
+}
+
+func putUpdateFuncCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := designFuncOpts(ctx, kouch.TargetUpdate, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if err := validateDesignTarget(o.Target, true); err != nil {
+		return err
+	}
+	if o.ContentType, err = cmd.Flags().GetString(flagContentType); err != nil {
+		return err
+	}
+	if err := util.ConfirmMutation(o, cmd.Flags(), fmt.Sprintf("You are about to call the update function '%s' in the database '%s'.", o.Function, o.Database), o.Database); err != nil {
+		return err
+	}
+	o.Body = kouch.Input(ctx)
+	method := http.MethodPost
+	if o.Document != "" {
+		method = http.MethodPut
+	}
+	return getRaw(ctx, method, util.DesignFuncPath(o, "_update"), o)
+}
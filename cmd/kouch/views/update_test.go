@@ -0,0 +1,36 @@
+package views
+
+import (
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func TestPutUpdateCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("no target", test.CmdTest{
+		Args:   []string{"--" + flagParam, "a=b"},
+		Err:    "No function name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("without document", func(t *testing.T) interface{} {
+		s := rawServer(t, "POST", "/foo/_design/bar/_update/baz?x=1", `{"a":1}`)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar/baz", "-d", `{"a":1}`, "--" + flagParam, "x=1"},
+			Stdout: "<h1>Hello</h1>\n",
+		}
+	})
+	tests.Add("with document", func(t *testing.T) interface{} {
+		s := rawServer(t, "PUT", "/foo/_design/bar/_update/baz/qux", "plain text")
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar/baz/qux", "-d", "plain text", "--" + flagContentType, "text/plain"},
+			Stdout: "<h1>Hello</h1>\n",
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"put", "update"}))
+}
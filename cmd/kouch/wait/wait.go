@@ -0,0 +1,218 @@
+package wait
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagFor     = "for"
+	flagTimeout = "timeout"
+)
+
+// Conditions which may be waited for.
+const (
+	conditionUp           = "up"
+	conditionDBExists     = "db-exists"
+	conditionClusterReady = "cluster-ready"
+)
+
+const defaultTimeout = time.Minute
+
+// Backoff limits between attempts. minBackoff is a variable, to allow tests
+// to run quickly.
+var minBackoff = 250 * time.Millisecond
+
+const maxBackoff = 5 * time.Second
+
+func init() {
+	registry.Register(nil, waitCmd)
+}
+
+func waitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "wait [target]",
+		Short: "Waits until the server is ready.",
+		Long: "Polls the server, with exponential backoff, until a condition holds, then exits with status 0. Supported conditions are:\n\n" +
+			"    " + conditionUp + "             The server responds successfully to /_up\n" +
+			"    " + conditionDBExists + "      The database exists\n" +
+			"    " + conditionClusterReady + "  All nodes of the cluster are connected, according to /_membership\n\n" +
+			"If the condition does not hold before the timeout expires, kouch exits with status 28, as curl does for timeouts. " +
+			"Use --verbose to show each attempt.\n\n" +
+			kouch.TargetHelpText(kouch.TargetRoot) + "\n\n" +
+			"When waiting for " + conditionDBExists + ", the target must include the database.",
+		RunE: waitForCmd,
+	}
+	f := cmd.Flags()
+	f.String(flagFor, conditionUp, "The condition to wait for: `"+conditionUp+"`, `"+conditionDBExists+"`, or `"+conditionClusterReady+"`.")
+	f.Duration(flagTimeout, defaultTimeout, "How long to wait before giving up. 0 waits forever.")
+	f.String(kouch.FlagDatabase, "", "The database, when waiting for "+conditionDBExists+". May be provided with the target in the format /{db}.")
+	return cmd
+}
+
+type waiter struct {
+	o         *kouch.Options
+	condition string
+	timeout   time.Duration
+}
+
+func waitForCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	w, err := waitOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	return w.wait(ctx)
+}
+
+func waitOpts(ctx context.Context, flags *pflag.FlagSet) (*waiter, error) {
+	w := &waiter{}
+	var err error
+	if w.condition, err = flags.GetString(flagFor); err != nil {
+		return nil, err
+	}
+	scope := kouch.TargetRoot
+	switch w.condition {
+	case conditionUp, conditionClusterReady:
+	case conditionDBExists:
+		scope = kouch.TargetDatabase
+	default:
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid condition '%s'. Supported options: `%s`, `%s`, `%s`",
+			w.condition, conditionUp, conditionDBExists, conditionClusterReady)
+	}
+	if w.o, err = util.CommonOptions(ctx, scope, flags); err != nil {
+		return nil, err
+	}
+	if scope == kouch.TargetDatabase && w.o.Database == "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if w.o.Root == "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	if w.timeout, err = flags.GetDuration(flagTimeout); err != nil {
+		return nil, err
+	}
+	if w.timeout < 0 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must not be negative", flagTimeout)
+	}
+	return w, nil
+}
+
+func (w *waiter) wait(ctx context.Context) error {
+	c, err := w.o.NewClient()
+	if err != nil {
+		return err
+	}
+	if w.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.timeout)
+		defer cancel()
+	}
+	backoff := minBackoff
+	var last error
+	for attempt := 1; ; attempt++ {
+		err := w.check(ctx, c)
+		if err == nil {
+			return nil
+		}
+		if kivik.StatusCode(err) == kivik.StatusBadAPICall {
+			return err
+		}
+		if ctx.Err() != nil {
+			// The attempt was interrupted by the timeout, so report why the
+			// previous attempt failed, if there was one.
+			if last == nil {
+				last = err
+			}
+			return w.timedOut(last)
+		}
+		last = err
+		if kouch.Verbose(ctx) {
+			_, _ = fmt.Fprintf(os.Stderr, "* Attempt %d: %s; retrying in %s\n", attempt, err, backoff)
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return w.timedOut(err)
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (w *waiter) timedOut(last error) error {
+	return errors.NewExitError(chttp.ExitOperationTimeout, "Timed out after %s waiting for %s: %s", w.timeout, w.condition, last)
+}
+
+// check returns nil if the condition holds, or an error describing why not.
+func (w *waiter) check(ctx context.Context, c *chttp.Client) error {
+	switch w.condition {
+	case conditionDBExists:
+		return w.do(ctx, c, http.MethodHead, util.DatabasePath(w.o), nil)
+	case conditionClusterReady:
+		var membership struct {
+			AllNodes     []string `json:"all_nodes"`
+			ClusterNodes []string `json:"cluster_nodes"`
+		}
+		if err := w.do(ctx, c, http.MethodGet, "/_membership", &membership); err != nil {
+			return err
+		}
+		return clusterReady(membership.AllNodes, membership.ClusterNodes)
+	}
+	return w.do(ctx, c, http.MethodGet, "/_up", nil)
+}
+
+// clusterReady returns nil if every node of the cluster is connected.
+func clusterReady(all, cluster []string) error {
+	if len(cluster) == 0 {
+		return fmt.Errorf("no cluster nodes")
+	}
+	connected := make(map[string]bool, len(all))
+	for _, node := range all {
+		connected[node] = true
+	}
+	var missing int
+	for _, node := range cluster {
+		if !connected[node] {
+			missing++
+		}
+	}
+	if missing > 0 {
+		return fmt.Errorf("%d of %d cluster nodes not connected", missing, len(cluster))
+	}
+	return nil
+}
+
+// do performs a request, and decodes the response into result, unless it is
+// nil.
+func (w *waiter) do(ctx context.Context, c *chttp.Client, method, path string, result interface{}) error {
+	res, err := c.DoReq(ctx, method, path, w.o.Options)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close() // nolint: errcheck
+	if err = chttp.ResponseError(res); err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return errors.WrapExitError(chttp.ExitWeirdReply, err)
+	}
+	return nil
+}
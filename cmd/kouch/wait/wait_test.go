@@ -0,0 +1,126 @@
+package wait
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestClusterReady(t *testing.T) {
+	tests := []struct {
+		name         string
+		all, cluster []string
+		err          string
+	}{
+		{name: "no nodes", err: "no cluster nodes"},
+		{name: "ready", all: []string{"a", "b"}, cluster: []string{"b", "a"}},
+		{name: "not ready", all: []string{"a"}, cluster: []string{"a", "b", "c"}, err: "2 of 3 cluster nodes not connected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testy.Error(t, tt.err, clusterReady(tt.all, tt.cluster))
+		})
+	}
+}
+
+func TestWaitCmd(t *testing.T) {
+	minBackoff = time.Millisecond
+	type waitTest struct {
+		test.CmdTest
+		requests func() []string
+		expected []string
+	}
+	tests := testy.NewTable()
+	tests.Add("invalid condition", waitTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost", "--" + flagFor, "down"},
+			Err:    "Invalid condition 'down'. Supported options: `up`, `db-exists`, `cluster-ready`",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("no root", waitTest{
+		CmdTest: test.CmdTest{
+			Err:    "No root URL provided",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("no database", waitTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"--root", "http://localhost", "--" + flagFor, conditionDBExists},
+			Err:    "No database name provided",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("negative timeout", waitTest{
+		CmdTest: test.CmdTest{
+			Args:   []string{"http://localhost", "--" + flagTimeout, "-1s"},
+			Err:    "--timeout must not be negative",
+			Status: chttp.ExitFailedToInitialize,
+		},
+	})
+	tests.Add("up after retries", func(t *testing.T) interface{} {
+		s := test.NewServer(t,
+			test.Response{Status: http.StatusServiceUnavailable, Body: `{"error":"unavailable"}`},
+			test.Response{Status: http.StatusNotFound, Body: `{"status":"maintenance_mode"}`},
+			test.Response{Status: http.StatusOK, Body: `{"status":"ok"}`},
+		)
+		tests.Cleanup(s.Close)
+		return waitTest{
+			CmdTest:  test.CmdTest{Args: []string{s.URL}},
+			requests: s.Requests,
+			expected: []string{"GET /_up ", "GET /_up ", "GET /_up "},
+		}
+	})
+	tests.Add("db exists", func(t *testing.T) interface{} {
+		s := test.NewServer(t,
+			test.Response{Status: http.StatusNotFound},
+			test.Response{Status: http.StatusOK},
+		)
+		tests.Cleanup(s.Close)
+		return waitTest{
+			CmdTest:  test.CmdTest{Args: []string{s.URL + "/foo", "--" + flagFor, conditionDBExists}},
+			requests: s.Requests,
+			expected: []string{"HEAD /foo ", "HEAD /foo "},
+		}
+	})
+	tests.Add("cluster ready", func(t *testing.T) interface{} {
+		s := test.NewServer(t,
+			test.Response{Status: http.StatusOK, Body: `{"all_nodes":["a"],"cluster_nodes":["a","b"]}`},
+			test.Response{Status: http.StatusOK, Body: `{"all_nodes":["a","b"],"cluster_nodes":["a","b"]}`},
+		)
+		tests.Cleanup(s.Close)
+		return waitTest{
+			CmdTest:  test.CmdTest{Args: []string{s.URL, "--" + flagFor, conditionClusterReady}},
+			requests: s.Requests,
+			expected: []string{"GET /_membership ", "GET /_membership "},
+		}
+	})
+	tests.Add("timeout", func(t *testing.T) interface{} {
+		s := test.NewRepeatServer(t, test.Response{Status: http.StatusNotFound, Body: `{"error":"not_found","reason":"Database does not exist."}`})
+		tests.Cleanup(s.Close)
+		return waitTest{
+			CmdTest: test.CmdTest{
+				Args:   []string{s.URL + "/foo", "--" + flagFor, conditionDBExists, "--" + flagTimeout, "20ms"},
+				Err:    "Timed out after 20ms waiting for db-exists: Not Found",
+				Status: chttp.ExitOperationTimeout,
+			},
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt waitTest) {
+		test.ValidateCmdTest([]string{"wait"})(t, tt.CmdTest)
+		if tt.requests == nil {
+			return
+		}
+		if d := diff.Interface(tt.expected, tt.requests()); d != nil {
+			t.Error(d)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 )
 
 // Config represents the kouch tool configuration.
@@ -32,6 +33,9 @@ type Context struct {
 	Root     string `json:"root"`
 	User     string `json:"user,omitempty"`
 	Password string `json:"password,omitempty"`
+	// Protected, when true, requires confirmation for every mutating
+	// command run against this context.
+	Protected bool `json:"protected,omitempty"`
 }
 
 // DefaultCtx returns the default context.
@@ -40,12 +44,45 @@ func (c *Config) DefaultCtx() (*Context, error) {
 	if name == "" {
 		return nil, InitError("No default context")
 	}
+	if ctx, ok := c.Ctx(name); ok {
+		return ctx, nil
+	}
+	return nil, InitError(fmt.Sprintf("Default context '%s' not defined", name))
+}
+
+// Ctx returns the named context, and true if it exists.
+func (c *Config) Ctx(name string) (*Context, bool) {
 	for _, nc := range c.Contexts {
 		if nc.Name == name {
-			return nc.Context, nil
+			return nc.Context, true
 		}
 	}
-	return nil, InitError(fmt.Sprintf("Default context '%s' not defined", name))
+	return nil, false
+}
+
+// ProtectedRoot returns true if root matches the root URL of any protected
+// context, so that targets given as a bare URL are protected too.
+func (c *Config) ProtectedRoot(root string) bool {
+	if c == nil || root == "" {
+		return false
+	}
+	root = normalizeRoot(root)
+	for _, nc := range c.Contexts {
+		if nc.Context != nil && nc.Context.Protected && normalizeRoot(nc.Context.Root) == root {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeRoot normalizes a root URL for comparison, by adding the default
+// scheme, if missing, and removing any trailing slash.
+func normalizeRoot(root string) string {
+	root = strings.ToLower(strings.TrimRight(root, "/"))
+	if !strings.Contains(root, "://") {
+		root = "http://" + root
+	}
+	return root
 }
 
 // Dump dumps the config as a JSON string on r. Any errors will be returned as
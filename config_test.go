@@ -43,3 +43,52 @@ func TestDefaultCtx(t *testing.T) {
 		})
 	}
 }
+
+func TestProtectedRoot(t *testing.T) {
+	conf := &Config{
+		Contexts: []NamedContext{
+			{Name: "prod", Context: &Context{Root: "https://couch.example.com/", Protected: true}},
+			{Name: "local", Context: &Context{Root: "localhost:5984", Protected: true}},
+			{Name: "dev", Context: &Context{Root: "http://dev.example.com"}},
+		},
+	}
+	tests := map[string]bool{
+		"":                            false,
+		"https://couch.example.com":   true,
+		"HTTPS://Couch.example.com/":  true,
+		"http://couch.example.com":    false,
+		"http://localhost:5984":       true,
+		"http://dev.example.com":      false,
+		"http://unknown.example.com/": false,
+	}
+	for root, expected := range tests {
+		if got := conf.ProtectedRoot(root); got != expected {
+			t.Errorf("ProtectedRoot(%q) = %t, expected %t", root, got, expected)
+		}
+	}
+	if (*Config)(nil).ProtectedRoot("http://localhost:5984") {
+		t.Error("Expected a nil config to protect nothing")
+	}
+}
+
+func TestCtx(t *testing.T) {
+	conf := &Config{
+		Contexts: []NamedContext{
+			{Name: "foo", Context: &Context{Root: "foo.com"}},
+		},
+	}
+	t.Run("found", func(t *testing.T) {
+		ctx, ok := conf.Ctx("foo")
+		if !ok {
+			t.Fatal("Expected context to be found")
+		}
+		if d := diff.Interface(&Context{Root: "foo.com"}, ctx); d != nil {
+			t.Error(d)
+		}
+	})
+	t.Run("not found", func(t *testing.T) {
+		if _, ok := conf.Ctx("bar"); ok {
+			t.Error("Expected context not to be found")
+		}
+	})
+}
@@ -1,6 +1,9 @@
 package kouch
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/go-kivik/couchdb/chttp"
 	"github.com/go-kivik/kouch/internal/errors"
 	"github.com/spf13/pflag"
@@ -32,6 +35,20 @@ const (
 	FlagRev                     = "rev"
 	FlagAutoRev                 = "auto-rev"
 	FlagShards                  = "shards"
+	FlagReplicas                = "replicas"
+	FlagPartitioned             = "partitioned"
+	FlagPlacement               = "placement"
+	FlagPartition               = "partition"
+	FlagYes                     = "yes"
+	FlagForceSystem             = "force-system"
+	FlagInputFormat             = "input-format"
+	FlagFeed                    = "feed"
+	FlagSince                   = "since"
+	FlagFilter                  = "filter"
+	FlagDocIDs                  = "doc-ids"
+	FlagSelector                = "selector"
+	FlagHeartbeat               = "heartbeat"
+	FlagStyle                   = "style"
 	FlagPassword                = "password"
 	FlagContext                 = "context"
 	FlagConflicts               = "conflicts"
@@ -61,6 +78,7 @@ const (
 	FlagJSONPrefix              = "json-prefix"
 	FlagJSONIndent              = "json-indent"
 	FlagJSONEscapeHTML          = "json-escape-html"
+	FlagPrometheusPrefix        = "prometheus-prefix"
 	FlagAttsSince               = "atts-since"
 	FlagIncludeDeletedConflicts = "deleted-conflicts"
 	FlagForceLatest             = "latest"
@@ -71,6 +89,11 @@ const (
 	FlagRevsInfo                = "revs-info"
 	FlagBatch                   = "batch"
 	FlagNewEdits                = "new-edits"
+	FlagAllOrNothing            = "all-or-nothing"
+	FlagWhere                   = "where"
+	FlagExplainSelector         = "explain-selector"
+	FlagView                    = "view"
+	FlagDesignDoc               = "ddoc"
 
 	// Curl-equivalent short flags
 	FlagShortVerbose    = "v"
@@ -86,6 +109,8 @@ const (
 	FlagShortRev          = "r"
 	FlagShortAutoRev      = "R"
 	FlagShortShards       = "q"
+	FlagShortReplicas     = "n"
+	FlagShortYes          = "y"
 	FlagShortPassword     = "p"
 )
 
@@ -100,11 +125,18 @@ var flagParsers = map[string]paramParser{
 	FlagStartKeyDocID:           parseParamString,
 	FlagUpdate:                  parseParamString,
 	FlagRev:                     parseParamString,
+	FlagPlacement:               parseParamString,
+	FlagFeed:                    parseParamString,
+	FlagSince:                   parseParamString,
+	FlagFilter:                  parseParamString,
+	FlagStyle:                   parseParamString,
 	FlagKeys:                    parseParamStringArray,
 	FlagGroupLevel:              parseParamInt,
 	FlagLimit:                   parseParamInt,
 	FlagSkip:                    parseParamInt,
 	FlagShards:                  parseParamInt,
+	FlagReplicas:                parseParamInt,
+	FlagHeartbeat:               parseParamInt,
 	FlagConflicts:               parseParamBool,
 	FlagDescending:              parseParamBool,
 	FlagGroup:                   parseParamBool,
@@ -125,6 +157,7 @@ var flagParsers = map[string]paramParser{
 	FlagRevs:                    parseParamBool,
 	FlagRevsInfo:                parseParamBool,
 	FlagNewEdits:                parseParamBool,
+	FlagPartitioned:             parseParamBool,
 }
 
 type paramValidator func(flag string, value []string) error
@@ -144,4 +177,44 @@ var flagValidators = map[string]paramValidator{
 		}
 		return errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid value for --%s. Supported options: `true`, `false`, `lazy`", flag)
 	},
+	FlagFeed: func(flag string, v []string) error {
+		switch v[0] {
+		case "normal", "longpoll", "continuous", "eventsource":
+			return nil
+		}
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid value for --%s. Supported options: `normal`, `longpoll`, `continuous`, `eventsource`", flag)
+	},
+	FlagStyle: func(flag string, v []string) error {
+		switch v[0] {
+		case "main_only", "all_docs":
+			return nil
+		}
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid value for --%s. Supported options: `main_only`, `all_docs`", flag)
+	},
+	FlagShards:    validatePositiveInt,
+	FlagReplicas:  validatePositiveInt,
+	FlagHeartbeat: validatePositiveInt,
+	FlagPlacement: validatePlacement,
+}
+
+func validatePositiveInt(flag string, v []string) error {
+	if i, err := strconv.Atoi(v[0]); err != nil || i < 1 {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid value for --%s. Must be a positive integer", flag)
+	}
+	return nil
+}
+
+// validatePlacement validates a placement rule, in the format
+// `zone:replicas[,zone:replicas...]`.
+func validatePlacement(flag string, v []string) error {
+	for _, rule := range strings.Split(v[0], ",") {
+		parts := strings.SplitN(rule, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid value for --%s. Expected format: `zone:replicas[,zone:replicas...]`", flag)
+		}
+		if err := validatePositiveInt(flag, parts[1:]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
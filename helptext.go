@@ -41,5 +41,58 @@ Except for _design/ and _local/ documents, any slashes in a database name or doc
   - http://host.com/foo/bar/baz.html -- Full URL
 
   Except for _design/ and _local/ documents, any slashes in a database name, document id, or filename must be URL-encoded.
+`,
+	TargetPartition: `[target] may be a full or relative URL to the partition. Examples:
+
+  - foo/bar                       -- Partition 'bar' in the database 'foo' at the default Root URL
+  - http://localhost:5984/foo/bar -- Full URL
+
+Any slashes in the database or partition name must be URL-encoded.
+`,
+	TargetView: `[target] may be a full or relative URL to the view. Examples:
+
+  - foo/_design/bar/_view/baz                   -- View 'baz' in the 'bar' design doc, in the database 'foo' at the default Root URL
+  - foo/bar/baz                                 -- Short form of the above
+  - bar/baz                                     -- View 'baz' in the 'bar' design doc, in the current database
+  - http://localhost:5984/foo/_design/bar/_view/baz -- Full URL
+
+Any slashes in the database, design document or view name must be URL-encoded.
+`,
+	TargetShow: `[target] may be a full or relative URL to the show function, optionally followed by a document ID. Examples:
+
+  - foo/_design/bar/_show/baz/qux -- Show function 'baz' in the 'bar' design doc, applied to the document 'qux', in the database 'foo'
+  - foo/bar/baz/qux               -- Short form of the above
+  - foo/bar/baz                   -- Show function 'baz', without a document
+  - bar/baz                       -- Show function 'baz' in the 'bar' design doc, in the current database
+  - http://localhost:5984/foo/_design/bar/_show/baz -- Full URL
+
+Any slashes in the database, design document or function name must be URL-encoded.
+`,
+	TargetList: `[target] may be a full or relative URL to the list function, followed by the view name. Examples:
+
+  - foo/_design/bar/_list/baz/qux     -- List function 'baz' in the 'bar' design doc, applied to the view 'qux' in the same design doc, in the database 'foo'
+  - foo/bar/baz/qux                   -- Short form of the above
+  - foo/bar/baz/other/qux             -- List function 'baz', applied to the view 'qux' in the 'other' design doc
+  - http://localhost:5984/foo/_design/bar/_list/baz/qux -- Full URL
+
+Any slashes in the database, design document, function or view name must be URL-encoded.
+`,
+	TargetUpdate: `[target] may be a full or relative URL to the update function, optionally followed by a document ID. Examples:
+
+  - foo/_design/bar/_update/baz/qux -- Update function 'baz' in the 'bar' design doc, applied to the document 'qux', in the database 'foo'
+  - foo/bar/baz/qux                 -- Short form of the above
+  - foo/bar/baz                     -- Update function 'baz', without a document
+  - bar/baz                         -- Update function 'baz' in the 'bar' design doc, in the current database
+  - http://localhost:5984/foo/_design/bar/_update/baz -- Full URL
+
+Any slashes in the database, design document or function name must be URL-encoded.
+`,
+	TargetRewrite: `[target] may be a full or relative URL to a path handled by the rewrite rules of a design document. Examples:
+
+  - foo/_design/bar/_rewrite/some/path -- The path 'some/path', rewritten by the 'bar' design doc in the database 'foo'
+  - foo/bar/some/path                  -- Short form of the above
+  - http://localhost:5984/foo/_design/bar/_rewrite/ -- Full URL
+
+The path is passed through as provided. Any slashes in the database or design document name must be URL-encoded.
 `,
 }
@@ -3,10 +3,12 @@ package test
 
 import (
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/flimzy/diff"
@@ -91,3 +93,63 @@ func Flags(t *testing.T, expected []string, cmd *cobra.Command) {
 		t.Error(d)
 	}
 }
+
+// Response is a canned response, served by a Server.
+type Response struct {
+	// Status defaults to 200 OK.
+	Status int
+	Body   string
+}
+
+// Server is an httptest.Server which responds to successive requests with
+// successive responses, recording each request as `METHOD URI BODY`.
+type Server struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+}
+
+// NewServer starts a Server. Requests received once the responses are
+// exhausted are reported as test errors.
+func NewServer(t *testing.T, responses ...Response) *Server {
+	return newServer(t, false, responses)
+}
+
+// NewRepeatServer starts a Server which repeats the final response once the
+// responses are exhausted.
+func NewRepeatServer(t *testing.T, responses ...Response) *Server {
+	return newServer(t, true, responses)
+}
+
+func newServer(t *testing.T, repeat bool, responses []Response) *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI()+" "+string(body))
+		i := len(s.requests) - 1
+		if i >= len(responses) {
+			if !repeat || len(responses) == 0 {
+				t.Errorf("Unexpected request: %s %s", r.Method, r.URL.RequestURI())
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			i = len(responses) - 1
+		}
+		res := responses[i]
+		w.Header().Set("Content-Type", "application/json")
+		if res.Status != 0 {
+			w.WriteHeader(res.Status)
+		}
+		_, _ = w.Write([]byte(res.Body))
+	}))
+	return s
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
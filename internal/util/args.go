@@ -0,0 +1,11 @@
+package util
+
+import "github.com/spf13/cobra"
+
+// PositionalArgs may be used as the PersistentPreRunE of a command which
+// interprets its positional arguments itself, rather than as a single target.
+// It runs the root command's pre-run without any arguments, so no target is
+// set.
+func PositionalArgs(cmd *cobra.Command, _ []string) error {
+	return cmd.Root().PersistentPreRunE(cmd, nil)
+}
@@ -0,0 +1,194 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/go-kivik/couchdb/chttp"
+)
+
+// BulkResult is a single row of a _bulk_docs response.
+type BulkResult struct {
+	ID     string `json:"id"`
+	Rev    string `json:"rev,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// DocSource is a stream of JSON documents. Next must return io.EOF when the
+// stream is exhausted.
+type DocSource interface {
+	Next() (json.RawMessage, error)
+}
+
+// DocSlice is a DocSource which reads documents from a slice.
+type DocSlice []json.RawMessage
+
+var _ DocSource = &DocSlice{}
+
+// Next returns the next document in the slice.
+func (s *DocSlice) Next() (json.RawMessage, error) {
+	if len(*s) == 0 {
+		return nil, io.EOF
+	}
+	doc := (*s)[0]
+	*s = (*s)[1:]
+	return doc, nil
+}
+
+// BulkUploader uploads a stream of documents to a database's _bulk_docs
+// endpoint, in batches, with one or more concurrent workers.
+type BulkUploader struct {
+	// Client is the client connected to the target server.
+	Client *chttp.Client
+	// Path is the path to the _bulk_docs endpoint.
+	Path string
+	// BatchSize is the maximum number of documents sent per request.
+	BatchSize int
+	// Workers is the number of concurrent requests.
+	Workers int
+	// PreserveRevisions instructs the server to store the documents'
+	// revisions as-is (new_edits=false).
+	PreserveRevisions bool
+	// AllOrNothing requests all-or-nothing semantics from the server. Only
+	// supported by CouchDB 1.x.
+	AllOrNothing bool
+	// OnResult, if set, is called for every result row returned by the
+	// server, along with the document that it refers to, if it could be
+	// identified. Calls are serialized.
+	OnResult func(doc json.RawMessage, result BulkResult) error
+}
+
+type bulkRequest struct {
+	Docs         []json.RawMessage `json:"docs"`
+	NewEdits     *bool             `json:"new_edits,omitempty"`
+	AllOrNothing bool              `json:"all_or_nothing,omitempty"`
+}
+
+// Upload reads all documents from src, and uploads them. It returns the number
+// of documents read. The first error encountered aborts the upload.
+func (u *BulkUploader) Upload(ctx context.Context, src DocSource) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	workers := u.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	var resultMU sync.Mutex
+	var firstErr error
+	var errOnce sync.Once
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	sem := make(chan struct{}, workers)
+	count, err := u.batch(ctx, src, func(batch []json.RawMessage) error {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results, err := u.post(ctx, batch)
+			if err != nil {
+				fail(err)
+				return
+			}
+			resultMU.Lock()
+			defer resultMU.Unlock()
+			if err := u.report(batch, results); err != nil {
+				fail(err)
+			}
+		}()
+		return nil
+	})
+	wg.Wait()
+	if err != nil && err != context.Canceled {
+		return count, err
+	}
+	return count, firstErr
+}
+
+// batch reads documents from src, passing them in batches to send.
+func (u *BulkUploader) batch(ctx context.Context, src DocSource, send func([]json.RawMessage) error) (int, error) {
+	var count int
+	batch := make([]json.RawMessage, 0, u.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := send(batch); err != nil {
+			return err
+		}
+		batch = make([]json.RawMessage, 0, u.BatchSize)
+		return nil
+	}
+	for {
+		doc, err := src.Next()
+		if err == io.EOF {
+			return count, flush()
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+		batch = append(batch, doc)
+		if len(batch) >= u.BatchSize {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+}
+
+func (u *BulkUploader) post(ctx context.Context, docs []json.RawMessage) ([]BulkResult, error) {
+	req := bulkRequest{
+		Docs:         docs,
+		AllOrNothing: u.AllOrNothing,
+	}
+	if u.PreserveRevisions {
+		f := false
+		req.NewEdits = &f
+	}
+	var results []BulkResult
+	_, err := u.Client.DoJSON(ctx, http.MethodPost, u.Path, &chttp.Options{
+		Body: chttp.EncodeBody(req),
+	}, &results)
+	return results, err
+}
+
+// report calls OnResult for each result. As results are only returned for
+// failed documents when new_edits=false, documents are matched to results by
+// ID, rather than by position.
+func (u *BulkUploader) report(docs []json.RawMessage, results []BulkResult) error {
+	if u.OnResult == nil {
+		return nil
+	}
+	byID := make(map[string]json.RawMessage, len(docs))
+	for i, doc := range docs {
+		var meta struct {
+			ID string `json:"_id"`
+		}
+		_ = json.Unmarshal(doc, &meta)
+		if meta.ID != "" {
+			byID[meta.ID] = doc
+		} else if i < len(results) && !u.PreserveRevisions {
+			byID[results[i].ID] = doc
+		}
+	}
+	for _, result := range results {
+		if err := u.OnResult(byID[result.ID], result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/kouchio"
+	"github.com/spf13/pflag"
+)
+
+// These are variables, rather than direct references to os.Stdin and
+// os.Stderr, to facilitate testing.
+var (
+	promptIn  io.Reader = os.Stdin
+	promptOut io.Writer = os.Stderr
+	isTTY               = stdinIsTerminal
+)
+
+func stdinIsTerminal() bool {
+	return fileIsTerminal(os.Stdin)
+}
+
+// fileIsTerminal is a portable approximation of isatty(): f is considered a
+// terminal if it is a character device other than the null device.
+func fileIsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(fi, null) {
+		return false
+	}
+	return true
+}
+
+// IsTerminal returns true if stdin is an interactive terminal.
+func IsTerminal() bool {
+	return isTTY()
+}
+
+// IsTerminalWriter returns true if w, once unwrapped, is an interactive
+// terminal.
+func IsTerminalWriter(w io.Writer) bool {
+	f, ok := kouchio.Underlying(w).(*os.File)
+	return ok && fileIsTerminal(f)
+}
+
+// Confirm asks the user to confirm a destructive operation by typing expected.
+// If the --yes flag is set, no confirmation is requested. If stdin is not a
+// terminal, an error is returned, as confirmation is impossible.
+func Confirm(flags *pflag.FlagSet, prompt, expected string) error {
+	if flags.Lookup(kouch.FlagYes) != nil {
+		yes, err := flags.GetBool(kouch.FlagYes)
+		if err != nil {
+			return err
+		}
+		if yes {
+			return nil
+		}
+	}
+	if !isTTY() {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "Confirmation required; use --%s to proceed non-interactively", kouch.FlagYes)
+	}
+	_, _ = fmt.Fprintf(promptOut, "%s\nType '%s' to confirm: ", prompt, expected)
+	answer, err := bufio.NewReader(promptIn).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	if strings.TrimSpace(answer) != expected {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "Confirmation failed; aborting")
+	}
+	return nil
+}
+
+// ConfirmMutation requests confirmation before a mutating operation, if the
+// target was configured from a protected context.
+func ConfirmMutation(o *kouch.Options, flags *pflag.FlagSet, prompt, expected string) error {
+	if !o.Protected {
+		return nil
+	}
+	return Confirm(flags, prompt+" The current context is protected.", expected)
+}
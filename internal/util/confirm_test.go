@@ -0,0 +1,84 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/spf13/pflag"
+)
+
+func TestConfirm(t *testing.T) {
+	type confirmTest struct {
+		args     []string
+		tty      bool
+		input    string
+		expected string
+		err      string
+		status   int
+	}
+	tests := testy.NewTable()
+	tests.Add("yes flag", confirmTest{
+		args: []string{"--" + kouch.FlagYes},
+	})
+	tests.Add("not a tty", confirmTest{
+		err:    "Confirmation required; use --yes to proceed non-interactively",
+		status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("confirmed", confirmTest{
+		tty:      true,
+		input:    "foo\n",
+		expected: "Delete?\nType 'foo' to confirm: ",
+	})
+	tests.Add("wrong answer", confirmTest{
+		tty:      true,
+		input:    "bar\n",
+		expected: "Delete?\nType 'foo' to confirm: ",
+		err:      "Confirmation failed; aborting",
+		status:   chttp.ExitFailedToInitialize,
+	})
+	tests.Add("no answer", confirmTest{
+		tty:      true,
+		expected: "Delete?\nType 'foo' to confirm: ",
+		err:      "Confirmation failed; aborting",
+		status:   chttp.ExitFailedToInitialize,
+	})
+
+	tests.Run(t, func(t *testing.T, test confirmTest) {
+		origIn, origOut, origTTY := promptIn, promptOut, isTTY
+		defer func() { promptIn, promptOut, isTTY = origIn, origOut, origTTY }()
+		out := &bytes.Buffer{}
+		promptIn = strings.NewReader(test.input)
+		promptOut = out
+		isTTY = func() bool { return test.tty }
+
+		flags := pflag.NewFlagSet("foo", pflag.ContinueOnError)
+		flags.Bool(kouch.FlagYes, false, "")
+		if err := flags.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		err := Confirm(flags, "Delete?", "foo")
+		testy.ExitStatusError(t, test.err, test.status, err)
+		if d := diff.Text(test.expected, out.String()); d != nil {
+			t.Error(d)
+		}
+	})
+}
+
+func TestConfirmMutation(t *testing.T) {
+	origTTY := isTTY
+	defer func() { isTTY = origTTY }()
+	isTTY = func() bool { return false }
+	flags := pflag.NewFlagSet("foo", pflag.ContinueOnError)
+	o := &kouch.Options{Target: &kouch.Target{Database: "foo"}}
+	if err := ConfirmMutation(o, flags, "Update?", "foo"); err != nil {
+		t.Errorf("Unexpected error for unprotected context: %s", err)
+	}
+	o.Protected = true
+	err := ConfirmMutation(o, flags, "Update?", "foo")
+	testy.ExitStatusError(t, "Confirmation required; use --yes to proceed non-interactively", chttp.ExitFailedToInitialize, err)
+}
@@ -3,6 +3,7 @@ package util
 import (
 	"fmt"
 	"net/url"
+	"strings"
 
 	"github.com/go-kivik/couchdb/chttp"
 	"github.com/go-kivik/kouch"
@@ -22,3 +23,52 @@ func AttPath(o *kouch.Options) string {
 func DatabasePath(o *kouch.Options) string {
 	return fmt.Sprintf("/%s", url.QueryEscape(o.Database))
 }
+
+// PartitionPath calculates the server path to a database partition.
+func PartitionPath(o *kouch.Options) string {
+	return fmt.Sprintf("/%s/_partition/%s", url.QueryEscape(o.Database), url.QueryEscape(o.Partition))
+}
+
+// EndpointPath calculates the server path to a database endpoint, such as
+// _all_docs or _find. If a partition is set, the path is scoped to that
+// partition.
+func EndpointPath(o *kouch.Options, endpoint string) string {
+	if o.Partition != "" {
+		return PartitionPath(o) + "/" + endpoint
+	}
+	return DatabasePath(o) + "/" + endpoint
+}
+
+// DesignDocPath calculates the server path to a design document.
+func DesignDocPath(o *kouch.Options) string {
+	return fmt.Sprintf("%s/_design/%s", DatabasePath(o), url.PathEscape(o.DesignDoc))
+}
+
+// ViewPath calculates the server path to a view. If a partition is set, the
+// path is scoped to that partition.
+func ViewPath(o *kouch.Options) string {
+	return EndpointPath(o, fmt.Sprintf("_design/%s/_view/%s", url.PathEscape(o.DesignDoc), url.PathEscape(o.View)))
+}
+
+// DesignFuncPath calculates the server path to a show, list, update or
+// rewrite function, where section is `_show`, `_list`, `_update` or
+// `_rewrite`.
+func DesignFuncPath(o *kouch.Options, section string) string {
+	path := DesignDocPath(o) + "/" + section
+	switch section {
+	case "_rewrite":
+		// The rewrite path is passed through as provided.
+		return path + "/" + o.Function
+	case "_list":
+		path += "/" + url.PathEscape(o.Function)
+		for _, part := range strings.Split(o.View, "/") {
+			path += "/" + url.PathEscape(part)
+		}
+		return path
+	}
+	path += "/" + url.PathEscape(o.Function)
+	if o.Document != "" {
+		path += "/" + chttp.EncodeDocID(o.Document)
+	}
+	return path
+}
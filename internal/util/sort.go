@@ -0,0 +1,27 @@
+package util
+
+import (
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/errors"
+)
+
+// ParseSort converts sort fields, in the format `field` or `field:asc|desc`,
+// to the format expected by _find.
+func ParseSort(fields []string) ([]interface{}, error) {
+	sort := make([]interface{}, len(fields))
+	for i, field := range fields {
+		colon := strings.LastIndex(field, ":")
+		if colon < 0 {
+			sort[i] = field
+			continue
+		}
+		dir := field[colon+1:]
+		if dir != "asc" && dir != "desc" {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid sort direction '%s' for field '%s'. Supported options: `asc`, `desc`", dir, field[:colon])
+		}
+		sort[i] = map[string]string{field[:colon]: dir}
+	}
+	return sort, nil
+}
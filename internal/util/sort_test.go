@@ -0,0 +1,37 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+)
+
+func TestParseSort(t *testing.T) {
+	type psTest struct {
+		fields   []string
+		expected []interface{}
+		err      string
+	}
+	tests := testy.NewTable()
+	tests.Add("plain", psTest{
+		fields:   []string{"name", "age"},
+		expected: []interface{}{"name", "age"},
+	})
+	tests.Add("directions", psTest{
+		fields:   []string{"name:asc", "age:desc"},
+		expected: []interface{}{map[string]string{"name": "asc"}, map[string]string{"age": "desc"}},
+	})
+	tests.Add("invalid direction", psTest{
+		fields: []string{"name:up"},
+		err:    "Invalid sort direction 'up' for field 'name'. Supported options: `asc`, `desc`",
+	})
+
+	tests.Run(t, func(t *testing.T, test psTest) {
+		sort, err := ParseSort(test.fields)
+		testy.Error(t, test.err, err)
+		if d := diff.Interface(test.expected, sort); d != nil {
+			t.Error(d)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/errors"
+)
+
+// StreamArray reads a single JSON object from r, and calls fn for each element
+// of the array found in the named field, without buffering the entire array in
+// memory. All other top-level fields are returned, undecoded.
+func StreamArray(r io.Reader, field string, fn func(json.RawMessage) error) (map[string]json.RawMessage, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	other := make(map[string]json.RawMessage)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, weirdReply(err)
+		}
+		key, _ := tok.(string)
+		if key != field {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, weirdReply(err)
+			}
+			other[key] = raw
+			continue
+		}
+		if err := streamElements(dec, fn); err != nil {
+			return nil, err
+		}
+	}
+	return other, expectDelim(dec, '}')
+}
+
+func streamElements(dec *json.Decoder, fn func(json.RawMessage) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return weirdReply(err)
+	}
+	if tok == nil {
+		return nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return errors.NewExitError(chttp.ExitWeirdReply, "expected JSON array, got %v", tok)
+	}
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return weirdReply(err)
+		}
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return weirdReply(err)
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return errors.NewExitError(chttp.ExitWeirdReply, "expected '%s' in JSON response, got %v", delim, tok)
+	}
+	return nil
+}
+
+func weirdReply(err error) error {
+	return errors.WrapExitError(chttp.ExitWeirdReply, err)
+}
+
+// StreamLines calls fn for each non-empty line read from r, such as from a
+// continuous changes feed. Empty lines, such as heartbeats, are skipped.
+func StreamLines(r io.Reader, fn func([]byte) error) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if e := fn(line); e != nil {
+				return e
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.WrapExitError(chttp.ExitReadError, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package util
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+)
+
+func TestStreamArray(t *testing.T) {
+	type saTest struct {
+		input    string
+		field    string
+		elements []string
+		other    map[string]json.RawMessage
+		err      string
+		status   int
+	}
+	tests := testy.NewTable()
+	tests.Add("all docs", saTest{
+		input:    `{"total_rows":2,"offset":0,"rows":[{"id":"a"},{"id":"b"}]}`,
+		field:    "rows",
+		elements: []string{`{"id":"a"}`, `{"id":"b"}`},
+		other: map[string]json.RawMessage{
+			"total_rows": json.RawMessage("2"),
+			"offset":     json.RawMessage("0"),
+		},
+	})
+	tests.Add("null array", saTest{
+		input: `{"rows":null}`,
+		field: "rows",
+		other: map[string]json.RawMessage{},
+	})
+	tests.Add("not an object", saTest{
+		input:  `[]`,
+		field:  "rows",
+		err:    "expected '{' in JSON response, got [",
+		status: chttp.ExitWeirdReply,
+	})
+	tests.Add("not an array", saTest{
+		input:  `{"rows":{}}`,
+		field:  "rows",
+		err:    "expected JSON array, got {",
+		status: chttp.ExitWeirdReply,
+	})
+	tests.Add("truncated", saTest{
+		input:  `{"rows":[{"id":"a"}`,
+		field:  "rows",
+		err:    "unexpected end of JSON input",
+		status: chttp.ExitWeirdReply,
+	})
+
+	tests.Run(t, func(t *testing.T, test saTest) {
+		var elements []string
+		other, err := StreamArray(strings.NewReader(test.input), test.field, func(raw json.RawMessage) error {
+			elements = append(elements, string(raw))
+			return nil
+		})
+		testy.ExitStatusError(t, test.err, test.status, err)
+		if d := diff.Interface(test.elements, elements); d != nil {
+			t.Error(d)
+		}
+		if d := diff.Interface(test.other, other); d != nil {
+			t.Error(d)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/errors"
+)
+
+// WriteTable writes a plain-text table, with aligned columns, to w.
+func WriteTable(w io.Writer, header []string, rows [][]string) error {
+	buf := &bytes.Buffer{}
+	tw := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	for _, row := range append([][]string{header}, rows...) {
+		_, _ = io.WriteString(tw, strings.Join(row, "\t")+"\n")
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	// Strip the padding of empty trailing columns.
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if _, err := io.WriteString(w, strings.TrimRight(line, " ")+"\n"); err != nil {
+			return errors.WrapExitError(chttp.ExitWriteError, err)
+		}
+	}
+	return nil
+}
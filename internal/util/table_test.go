@@ -0,0 +1,23 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/flimzy/diff"
+)
+
+func TestWriteTable(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := WriteTable(buf, []string{"ID", "REV", "ERROR"}, [][]string{
+		{"a", "1-x", ""},
+		{"bbbb", "", "conflict"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "ID    REV  ERROR\na     1-x\nbbbb       conflict\n"
+	if d := diff.Text(expected, buf.String()); d != nil {
+		t.Error(d)
+	}
+}
@@ -0,0 +1,21 @@
+package util
+
+import (
+	"path"
+	"strings"
+)
+
+// TaskDatabase returns the database name from the database of an active task,
+// which, in a cluster, is a shard, such as
+// `shards/00000000-1fffffff/db.1530000000`.
+func TaskDatabase(db string) string {
+	if !strings.HasPrefix(db, "shards/") {
+		return db
+	}
+	name := strings.TrimPrefix(db, "shards/")
+	name = name[strings.Index(name, "/")+1:]
+	if ext := path.Ext(name); ext != "" {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
+}
@@ -0,0 +1,16 @@
+package util
+
+import "testing"
+
+func TestTaskDatabase(t *testing.T) {
+	tests := map[string]string{
+		"db":                                     "db",
+		"shards/00000000-1fffffff/db.1530000000": "db",
+		"shards/00000000-1fffffff/a/b.1530000000": "a/b",
+	}
+	for in, expected := range tests {
+		if got := TaskDatabase(in); got != expected {
+			t.Errorf("TaskDatabase(%q) = %q, expected %q", in, got, expected)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/kouchio"
+	"github.com/spf13/pflag"
+)
+
+// whereOps maps the operators supported by --where to their Mango
+// equivalents, longest first, so that `>=` is matched before `>`.
+var whereOps = []struct {
+	op, mango string
+}{
+	{">=", "$gte"},
+	{"<=", "$lte"},
+	{"!=", "$ne"},
+	{"=~", "$regex"},
+	{"=", "$eq"},
+	{">", "$gt"},
+	{"<", "$lt"},
+	{"~", "$all"},
+}
+
+// AddWhereFlags adds the --where and --explain-selector flags.
+func AddWhereFlags(f *pflag.FlagSet) {
+	f.StringArray(kouch.FlagWhere, nil, "A condition, such as `type=user`, `age>=30`, `name=~^J`, `tags~admin` or `email exists`, from which to build a selector. May be repeated; all conditions must match.")
+	f.Bool(kouch.FlagExplainSelector, false, "Print the selector generated by --"+kouch.FlagWhere+", and exit.")
+}
+
+// WhereSelector returns the selector built from the --where flags, or nil if
+// none were given.
+func WhereSelector(flags *pflag.FlagSet) (map[string]interface{}, error) {
+	exprs, err := flags.GetStringArray(kouch.FlagWhere)
+	if err != nil {
+		return nil, err
+	}
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	return ParseWhere(exprs)
+}
+
+// AndSelectors combines two selectors, either of which may be empty, such that
+// documents must match both.
+func AndSelectors(a, b interface{}) interface{} {
+	if isEmptySelector(a) {
+		return b
+	}
+	if isEmptySelector(b) {
+		return a
+	}
+	return map[string]interface{}{"$and": []interface{}{a, b}}
+}
+
+func isEmptySelector(s interface{}) bool {
+	switch t := s.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(t) == 0
+	}
+	return false
+}
+
+// ExplainSelector writes selector to the output, and returns true, if
+// --explain-selector was given.
+func ExplainSelector(ctx context.Context, flags *pflag.FlagSet, selector interface{}) (bool, error) {
+	if flags.Lookup(kouch.FlagExplainSelector) == nil {
+		return false, nil
+	}
+	explain, err := flags.GetBool(kouch.FlagExplainSelector)
+	if err != nil || !explain {
+		return false, err
+	}
+	out := kouch.Output(ctx)
+	if err := json.NewEncoder(out).Encode(selector); err != nil {
+		return true, errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	return true, kouchio.CloseWriter(out)
+}
+
+// ParseWhere compiles simple conditions, in the format `field op value` or
+// `field exists`, to a Mango selector.
+func ParseWhere(exprs []string) (map[string]interface{}, error) {
+	selector := make(map[string]interface{})
+	for _, expr := range exprs {
+		field, op, value, err := parseCondition(expr)
+		if err != nil {
+			return nil, err
+		}
+		cond, _ := selector[field].(map[string]interface{})
+		if cond == nil {
+			cond = make(map[string]interface{})
+			selector[field] = cond
+		}
+		if existing, ok := cond[op]; ok {
+			if op != "$all" {
+				return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Conflicting conditions for field '%s'", field)
+			}
+			value = append(existing.([]interface{}), value.([]interface{})...)
+		}
+		cond[op] = value
+	}
+	return selector, nil
+}
+
+// parseCondition parses a single condition, returning the field, the Mango
+// operator, and the operand.
+func parseCondition(expr string) (field, op string, value interface{}, err error) {
+	if parts := strings.Fields(expr); len(parts) == 2 {
+		switch parts[1] {
+		case "exists":
+			return parts[0], "$exists", true, nil
+		case "missing":
+			return parts[0], "$exists", false, nil
+		}
+	}
+	for i := range expr {
+		for _, o := range whereOps {
+			if !strings.HasPrefix(expr[i:], o.op) {
+				continue
+			}
+			field = strings.TrimSpace(expr[:i])
+			if field == "" {
+				return "", "", nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid condition '%s': no field name", expr)
+			}
+			raw := strings.TrimSpace(expr[i+len(o.op):])
+			switch o.mango {
+			case "$regex":
+				return field, o.mango, unquote(raw), nil
+			case "$all":
+				return field, o.mango, []interface{}{inferValue(raw)}, nil
+			}
+			return field, o.mango, inferValue(raw), nil
+		}
+	}
+	return "", "", nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid condition '%s': no operator found", expr)
+}
+
+var numberRE = regexp.MustCompile(`^-?(0|[1-9]\d*)(\.\d+)?([eE][+-]?\d+)?$`)
+
+// inferValue converts raw to a number, boolean or null, if it looks like one.
+// Quoted values are always strings.
+func inferValue(raw string) interface{} {
+	switch {
+	case raw == "true":
+		return true
+	case raw == "false":
+		return false
+	case raw == "null":
+		return nil
+	case numberRE.MatchString(raw):
+		return json.Number(raw)
+	}
+	return unquote(raw)
+}
+
+// unquote removes matching single or double quotes surrounding s.
+func unquote(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	switch s[0] {
+	case '"':
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	case '\'':
+		if s[len(s)-1] == '\'' {
+			return s[1 : len(s)-1]
+		}
+	}
+	return s
+}
@@ -0,0 +1,89 @@
+package util
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+)
+
+func TestParseWhere(t *testing.T) {
+	type pwTest struct {
+		exprs    []string
+		expected string
+		err      string
+	}
+	tests := testy.NewTable()
+	tests.Add("equality", pwTest{
+		exprs:    []string{"type=user"},
+		expected: `{"type":{"$eq":"user"}}`,
+	})
+	tests.Add("comparisons", pwTest{
+		exprs:    []string{"age>=30", "age < 40", "score>1.5e3", "rank<=-2", "status!=active"},
+		expected: `{"age":{"$gte":30,"$lt":40},"rank":{"$lte":-2},"score":{"$gt":1.5e3},"status":{"$ne":"active"}}`,
+	})
+	tests.Add("type inference", pwTest{
+		exprs:    []string{"a=true", "b=false", "c=null", `d="42"`, "e='true'", "f=007", "g=1.2.3"},
+		expected: `{"a":{"$eq":true},"b":{"$eq":false},"c":{"$eq":null},"d":{"$eq":"42"},"e":{"$eq":"true"},"f":{"$eq":"007"},"g":{"$eq":"1.2.3"}}`,
+	})
+	tests.Add("regex", pwTest{
+		exprs:    []string{"name=~^J.*n$", "code=~123"},
+		expected: `{"code":{"$regex":"123"},"name":{"$regex":"^J.*n$"}}`,
+	})
+	tests.Add("contains", pwTest{
+		exprs:    []string{"tags~admin", "tags~ops"},
+		expected: `{"tags":{"$all":["admin","ops"]}}`,
+	})
+	tests.Add("exists", pwTest{
+		exprs:    []string{"email exists", "phone missing"},
+		expected: `{"email":{"$exists":true},"phone":{"$exists":false}}`,
+	})
+	tests.Add("nested field", pwTest{
+		exprs:    []string{"address.city=Paris"},
+		expected: `{"address.city":{"$eq":"Paris"}}`,
+	})
+	tests.Add("value containing operator", pwTest{
+		exprs:    []string{"eq=a=b"},
+		expected: `{"eq":{"$eq":"a=b"}}`,
+	})
+	tests.Add("no operator", pwTest{
+		exprs: []string{"email"},
+		err:   "Invalid condition 'email': no operator found",
+	})
+	tests.Add("no field", pwTest{
+		exprs: []string{"=foo"},
+		err:   "Invalid condition '=foo': no field name",
+	})
+	tests.Add("conflict", pwTest{
+		exprs: []string{"type=user", "type=admin"},
+		err:   "Conflicting conditions for field 'type'",
+	})
+
+	tests.Run(t, func(t *testing.T, test pwTest) {
+		selector, err := ParseWhere(test.exprs)
+		testy.Error(t, test.err, err)
+		result, err := json.Marshal(selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := diff.JSON([]byte(test.expected), result); d != nil {
+			t.Error(d)
+		}
+	})
+}
+
+func TestAndSelectors(t *testing.T) {
+	a := map[string]interface{}{"a": 1}
+	b := map[string]interface{}{"b": 2}
+	if d := diff.Interface(b, AndSelectors(nil, b)); d != nil {
+		t.Error(d)
+	}
+	if d := diff.Interface(a, AndSelectors(a, map[string]interface{}{})); d != nil {
+		t.Error(d)
+	}
+	expected := map[string]interface{}{"$and": []interface{}{a, b}}
+	if d := diff.Interface(expected, AndSelectors(a, b)); d != nil {
+		t.Error(d)
+	}
+}
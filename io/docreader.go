@@ -0,0 +1,143 @@
+package io
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/icza/dyno"
+	"github.com/spf13/pflag"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Supported input formats for document streams.
+const (
+	InputFormatJSON = "json"
+	InputFormatYAML = "yaml"
+)
+
+// DocInputFormat returns the value of --input-format. If that flag is unset,
+// and --data names a file with a .yaml or .yml extension, InputFormatYAML is
+// returned.
+func DocInputFormat(flags *pflag.FlagSet) (string, error) {
+	format, err := flags.GetString(kouch.FlagInputFormat)
+	if err != nil || flags.Changed(kouch.FlagInputFormat) {
+		return format, err
+	}
+	if data := flags.Lookup(kouch.FlagData); data != nil && strings.HasPrefix(data.Value.String(), "@") {
+		switch strings.ToLower(filepath.Ext(data.Value.String())) {
+		case ".yaml", ".yml":
+			return InputFormatYAML, nil
+		}
+	}
+	return format, nil
+}
+
+// DocReader reads a stream of documents, one at a time.
+type DocReader interface {
+	// Next returns the next document, JSON-encoded. io.EOF is returned when
+	// no more documents remain.
+	Next() (json.RawMessage, error)
+}
+
+// NewDocReader returns a DocReader which reads documents from r.
+//
+// When format is InputFormatJSON, r may contain a JSON array of documents, or
+// a sequence of JSON documents, such as JSON Lines. When format is
+// InputFormatYAML, r may contain a YAML sequence of documents, or multiple
+// YAML documents, separated by `---`.
+func NewDocReader(r io.Reader, format string) (DocReader, error) {
+	switch format {
+	case InputFormatJSON:
+		return &jsonDocReader{r: bufio.NewReader(r)}, nil
+	case InputFormatYAML:
+		return &yamlDocReader{dec: yaml.NewDecoder(r)}, nil
+	}
+	return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Unrecognized input format '%s'", format)
+}
+
+type jsonDocReader struct {
+	r       *bufio.Reader
+	dec     *json.Decoder
+	inArray bool
+}
+
+var _ DocReader = &jsonDocReader{}
+
+func (r *jsonDocReader) init() error {
+	for {
+		c, _, err := r.r.ReadRune()
+		if err != nil {
+			return err
+		}
+		if unicode.IsSpace(c) {
+			continue
+		}
+		if err := r.r.UnreadRune(); err != nil {
+			return err
+		}
+		r.dec = json.NewDecoder(r.r)
+		if c != '[' {
+			return nil
+		}
+		r.inArray = true
+		_, err = r.dec.Token()
+		return err
+	}
+}
+
+func (r *jsonDocReader) Next() (json.RawMessage, error) {
+	if r.dec == nil {
+		if err := r.init(); err != nil {
+			return nil, readError(err)
+		}
+	}
+	if r.inArray && !r.dec.More() {
+		return nil, io.EOF
+	}
+	var doc json.RawMessage
+	if err := r.dec.Decode(&doc); err != nil {
+		return nil, readError(err)
+	}
+	return doc, nil
+}
+
+type yamlDocReader struct {
+	dec     *yaml.Decoder
+	pending []interface{}
+}
+
+var _ DocReader = &yamlDocReader{}
+
+func (r *yamlDocReader) Next() (json.RawMessage, error) {
+	for len(r.pending) == 0 {
+		var doc interface{}
+		if err := r.dec.Decode(&doc); err != nil {
+			return nil, readError(err)
+		}
+		if list, ok := doc.([]interface{}); ok {
+			r.pending = list
+			continue
+		}
+		if doc != nil {
+			r.pending = []interface{}{doc}
+		}
+	}
+	doc := r.pending[0]
+	r.pending = r.pending[1:]
+	enc, err := json.Marshal(dyno.ConvertMapI2MapS(doc))
+	return enc, errors.WrapExitError(chttp.ExitReadError, err)
+}
+
+func readError(err error) error {
+	if err == io.EOF {
+		return err
+	}
+	return errors.WrapExitError(chttp.ExitReadError, err)
+}
@@ -0,0 +1,126 @@
+package io
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/spf13/pflag"
+)
+
+func TestDocReader(t *testing.T) {
+	type drTest struct {
+		format   string
+		input    string
+		expected []string
+		err      string
+		status   int
+	}
+	tests := testy.NewTable()
+	tests.Add("invalid format", drTest{
+		format: "oink",
+		err:    "Unrecognized input format 'oink'",
+		status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("empty json", drTest{
+		format: InputFormatJSON,
+		input:  "  \n",
+	})
+	tests.Add("json lines", drTest{
+		format:   InputFormatJSON,
+		input:    "{\"_id\":\"a\"}\n{\"_id\":\"b\"}\n",
+		expected: []string{`{"_id":"a"}`, `{"_id":"b"}`},
+	})
+	tests.Add("json array", drTest{
+		format:   InputFormatJSON,
+		input:    "\n [{\"_id\":\"a\"},\n{\"_id\":\"b\"}]",
+		expected: []string{`{"_id":"a"}`, `{"_id":"b"}`},
+	})
+	tests.Add("invalid json", drTest{
+		format: InputFormatJSON,
+		input:  `{"_id":"a"}{"_id":`,
+		err:    "unexpected EOF",
+		status: chttp.ExitReadError,
+	})
+	tests.Add("yaml multi-doc", drTest{
+		format:   InputFormatYAML,
+		input:    "_id: a\n---\n_id: b\nfoo:\n  bar: 1\n",
+		expected: []string{`{"_id":"a"}`, `{"_id":"b","foo":{"bar":1}}`},
+	})
+	tests.Add("yaml sequence", drTest{
+		format:   InputFormatYAML,
+		input:    "- _id: a\n- _id: b\n",
+		expected: []string{`{"_id":"a"}`, `{"_id":"b"}`},
+	})
+
+	tests.Run(t, func(t *testing.T, test drTest) {
+		r, err := NewDocReader(strings.NewReader(test.input), test.format)
+		var result []string
+		for err == nil {
+			var doc json.RawMessage
+			doc, err = r.Next()
+			if err == nil {
+				result = append(result, string(doc))
+			}
+		}
+		if err == io.EOF {
+			err = nil
+		}
+		testy.ExitStatusError(t, test.err, test.status, err)
+		if d := diff.Interface(test.expected, result); d != nil {
+			t.Error(d)
+		}
+	})
+}
+
+func TestDocInputFormat(t *testing.T) {
+	type difTest struct {
+		args     []string
+		expected string
+	}
+	tests := testy.NewTable()
+	tests.Add("default", difTest{
+		expected: InputFormatJSON,
+	})
+	tests.Add("explicit", difTest{
+		args:     []string{"--" + kouch.FlagInputFormat, "yaml"},
+		expected: InputFormatYAML,
+	})
+	tests.Add("yaml file", difTest{
+		args:     []string{"--" + kouch.FlagData, "@docs.YML"},
+		expected: InputFormatYAML,
+	})
+	tests.Add("json file", difTest{
+		args:     []string{"--" + kouch.FlagData, "@docs.json"},
+		expected: InputFormatJSON,
+	})
+	tests.Add("literal data", difTest{
+		args:     []string{"--" + kouch.FlagData, "foo.yaml"},
+		expected: InputFormatJSON,
+	})
+	tests.Add("explicit overrides extension", difTest{
+		args:     []string{"--" + kouch.FlagInputFormat, "json", "--" + kouch.FlagData, "@docs.yaml"},
+		expected: InputFormatJSON,
+	})
+
+	tests.Run(t, func(t *testing.T, test difTest) {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.String(kouch.FlagInputFormat, InputFormatJSON, "")
+		flags.String(kouch.FlagData, "", "")
+		if err := flags.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		format, err := DocInputFormat(flags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format != test.expected {
+			t.Errorf("Unexpected format: %s", format)
+		}
+	})
+}
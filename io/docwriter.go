@@ -0,0 +1,75 @@
+package io
+
+import (
+	"context"
+	"io"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/kouchio"
+)
+
+// DocWriter writes a stream of documents to the output, formatting each one
+// independently, according to the selected output format.
+type DocWriter struct {
+	ctx    context.Context
+	w      io.Writer
+	mode   kouchio.OutputMode
+	format string
+	count  int
+}
+
+// NewDocWriter returns a DocWriter which writes to the output configured in
+// ctx.
+func NewDocWriter(ctx context.Context) (*DocWriter, error) {
+	format, err := kouch.Flags(ctx).GetString(kouch.FlagOutputFormat)
+	if err != nil {
+		return nil, err
+	}
+	mode, ok := outputModes[format]
+	if !ok {
+		return nil, errors.Errorf("Unrecognized output format '%s'", format)
+	}
+	return &DocWriter{
+		ctx:    ctx,
+		w:      kouchio.Underlying(kouch.Output(ctx)),
+		mode:   mode,
+		format: format,
+	}, nil
+}
+
+// WriteDoc formats and writes a single JSON-encoded document.
+func (w *DocWriter) WriteDoc(doc []byte) error {
+	if w.format == "yaml" && w.count > 0 {
+		if _, err := io.WriteString(w.w, "---\n"); err != nil {
+			return errors.WrapExitError(chttp.ExitWriteError, err)
+		}
+	}
+	w.count++
+	p, err := w.mode.New(w.ctx, w.w)
+	if err != nil {
+		return err
+	}
+	if _, err := p.Write(doc); err != nil {
+		return err
+	}
+	return kouchio.CloseWriter(p)
+}
+
+// ParseData parses data, given with the named flag, in YAML or JSON format,
+// into an arbitrary data structure. If data begins with '@', the remainder is
+// treated as a filename from which the data is read.
+func ParseData(flag, data string) (interface{}, error) {
+	in, err := openData(data)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close() // nolint: errcheck
+	// As YAML is a superset of JSON, the YAML parser handles both.
+	i, err := convertData(in, kouch.FlagDataYAML)
+	if err != nil {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s: %s", flag, err)
+	}
+	return i, nil
+}
@@ -0,0 +1,45 @@
+package io
+
+import (
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+)
+
+func TestParseData(t *testing.T) {
+	type pdTest struct {
+		data     string
+		expected interface{}
+		err      string
+		status   int
+	}
+	tests := testy.NewTable()
+	tests.Add("json", pdTest{
+		data:     `{"type":"user","age":{"$gt":30}}`,
+		expected: map[string]interface{}{"type": "user", "age": map[string]interface{}{"$gt": 30}},
+	})
+	tests.Add("yaml", pdTest{
+		data:     "type: user\nage:\n  $gt: 30\n",
+		expected: map[string]interface{}{"type": "user", "age": map[string]interface{}{"$gt": 30}},
+	})
+	tests.Add("invalid", pdTest{
+		data:   "{",
+		err:    "--selector: yaml: line 1: did not find expected node content",
+		status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("missing file", pdTest{
+		data:   "@/nonexistent/selector.yaml",
+		err:    "open /nonexistent/selector.yaml: no such file or directory",
+		status: chttp.ExitReadError,
+	})
+
+	tests.Run(t, func(t *testing.T, test pdTest) {
+		result, err := ParseData("selector", test.data)
+		testy.ExitStatusError(t, test.err, test.status, err)
+		if d := diff.Interface(test.expected, result); d != nil {
+			t.Error(d)
+		}
+	})
+}
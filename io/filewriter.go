@@ -26,7 +26,12 @@ func (w *delayedOpenWriter) Write(p []byte) (int, error) {
 	return w.w.Write(p)
 }
 
+// Close closes the file, if it was opened. If nothing was written, no file
+// is created.
 func (w *delayedOpenWriter) Close() error {
+	if w.w == nil {
+		return nil
+	}
 	return w.w.Close()
 }
 
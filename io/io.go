@@ -164,10 +164,10 @@ func RedirStderr(flags *pflag.FlagSet) error {
 }
 
 // whichInput returns the input flag which was set, and the flag value
-func whichInput(cmd *cobra.Command) (flag, value string, err error) {
+func whichInput(flags *pflag.FlagSet) (flag, value string, err error) {
 	var found int
 	for _, f := range []string{kouch.FlagData, kouch.FlagDataJSON, kouch.FlagDataYAML} {
-		v, err := cmd.Flags().GetString(f)
+		v, err := flags.GetString(f)
 		if err != nil {
 			return "", "", err
 		}
@@ -183,9 +183,22 @@ func whichInput(cmd *cobra.Command) (flag, value string, err error) {
 	return flag, value, nil
 }
 
+// openData returns an io.ReadCloser for data. If data begins with '@', the
+// remainder is treated as a filename from which the data is read.
+func openData(data string) (io.ReadCloser, error) {
+	if strings.HasPrefix(data, "@") {
+		f, err := os.Open(data[1:])
+		if err != nil {
+			return nil, errors.WrapExitError(chttp.ExitReadError, err)
+		}
+		return f, nil
+	}
+	return ioutil.NopCloser(strings.NewReader(data)), nil
+}
+
 // SelectInput returns an io.ReadCloser for the input.
 func SelectInput(cmd *cobra.Command) (io.ReadCloser, error) {
-	flag, data, err := whichInput(cmd)
+	flag, data, err := whichInput(cmd.Flags())
 	if err != nil {
 		return nil, err
 	}
@@ -193,14 +206,9 @@ func SelectInput(cmd *cobra.Command) (io.ReadCloser, error) {
 		// Default to stdin
 		return os.Stdin, nil
 	}
-	var in io.ReadCloser
-	if data[0] == '@' {
-		in, err = os.Open(data[1:])
-		if err != nil {
-			return nil, errors.WrapExitError(chttp.ExitReadError, err)
-		}
-	} else {
-		in = ioutil.NopCloser(strings.NewReader(data))
+	in, err := openData(data)
+	if err != nil {
+		return nil, err
 	}
 	if flag == kouch.FlagData {
 		return in, nil
@@ -218,23 +226,67 @@ func SelectInput(cmd *cobra.Command) (io.ReadCloser, error) {
 	return r, nil
 }
 
+// MultipleDataDocs returns the name of the data flag, --data-json or
+// --data-yaml, which was set to more than one document, or "" if neither was.
+// Only the first document is read as input, so commands which accept multiple
+// documents should reject such input.
+func MultipleDataDocs(flags *pflag.FlagSet) (string, error) {
+	flag, data, err := whichInput(flags)
+	if err != nil || data == "" || flag == kouch.FlagData {
+		return "", err
+	}
+	in, err := openData(data)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close() // nolint: errcheck
+	dec := newDecoder(in, flag)
+	for count := 0; ; count++ {
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil {
+			if err == io.EOF {
+				return "", nil
+			}
+			return "", errors.WrapExitError(chttp.ExitPostError, err)
+		}
+		if count > 0 {
+			return flag, nil
+		}
+	}
+}
+
+type decoder interface {
+	Decode(interface{}) error
+}
+
+// newDecoder returns a decoder for data read from in, according to the format
+// in flag.
+func newDecoder(in io.Reader, flag string) decoder {
+	switch flag {
+	case kouch.FlagDataJSON:
+		return json.NewDecoder(in)
+	case kouch.FlagDataYAML:
+		return yaml.NewDecoder(in)
+	}
+	panic("Unknown flag: " + flag)
+}
+
+// DecodeData decodes a single document, in YAML or JSON format, read from r.
+// As YAML is a superset of JSON, both are decoded as with --data-yaml.
+func DecodeData(r io.Reader) (interface{}, error) {
+	var i interface{}
+	if err := newDecoder(r, kouch.FlagDataYAML).Decode(&i); err != nil {
+		return nil, err
+	}
+	return dyno.ConvertMapI2MapS(i), nil
+}
+
 // convertData converts data read from in, according to the format in flag,
 // to an arbitrary data structure.
 func convertData(in io.Reader, flag string) (interface{}, error) {
 	var i interface{}
-	switch flag {
-	case kouch.FlagDataJSON:
-		if err := json.NewDecoder(in).Decode(&i); err != nil {
-			return nil, errors.WrapExitError(chttp.ExitPostError, err)
-		}
-	case kouch.FlagDataYAML:
-		var j interface{}
-		if err := yaml.NewDecoder(in).Decode(&j); err != nil {
-			return nil, errors.WrapExitError(chttp.ExitPostError, err)
-		}
-		i = dyno.ConvertMapI2MapS(j)
-	default:
-		panic("Unknown flag: " + flag)
+	if err := newDecoder(in, flag).Decode(&i); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitPostError, err)
 	}
-	return i, nil
+	return dyno.ConvertMapI2MapS(i), nil
 }
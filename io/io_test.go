@@ -21,7 +21,7 @@ func TestAddFlags(t *testing.T) {
 	cmd := &cobra.Command{}
 	AddFlags(cmd.PersistentFlags())
 
-	test.Flags(t, []string{"create-dirs", "data", "data-json", "data-yaml", "dump-header", "force", "json-escape-html", "json-indent", "json-prefix", "output", "output-format", "stderr", "template", "template-file"}, cmd)
+	test.Flags(t, []string{"create-dirs", "data", "data-json", "data-yaml", "dump-header", "force", "json-escape-html", "json-indent", "json-prefix", "output", "output-format", "prometheus-prefix", "stderr", "template", "template-file"}, cmd)
 }
 
 func TestSelectOutputProcessor(t *testing.T) {
@@ -520,6 +520,11 @@ func TestSelectInput(t *testing.T) {
 			args:     []string{"--" + kouch.FlagDataYAML, `_id: foo`},
 			expected: `{"_id":"foo"}`,
 		},
+		{
+			name:     "multiple yaml documents",
+			args:     []string{"--" + kouch.FlagDataYAML, "_id: foo\n---\n_id: bar\n"},
+			expected: `{"_id":"foo"}`,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -543,3 +548,46 @@ func TestSelectInput(t *testing.T) {
 		})
 	}
 }
+
+func TestMultipleDataDocs(t *testing.T) {
+	type mddTest struct {
+		args     []string
+		expected string
+		err      string
+		status   int
+	}
+	tests := testy.NewTable()
+	tests.Add("no data", mddTest{})
+	tests.Add("raw data", mddTest{
+		args: []string{"--" + kouch.FlagData, `{"_id":"foo"} {"_id":"bar"}`},
+	})
+	tests.Add("single json document", mddTest{
+		args: []string{"--" + kouch.FlagDataJSON, `[{"_id":"foo"},{"_id":"bar"}]`},
+	})
+	tests.Add("multiple json documents", mddTest{
+		args:     []string{"--" + kouch.FlagDataJSON, `{"_id":"foo"} {"_id":"bar"}`},
+		expected: kouch.FlagDataJSON,
+	})
+	tests.Add("multiple yaml documents", mddTest{
+		args:     []string{"--" + kouch.FlagDataYAML, "_id: foo\n---\n_id: bar\n"},
+		expected: kouch.FlagDataYAML,
+	})
+	tests.Add("trailing invalid json", mddTest{
+		args:   []string{"--" + kouch.FlagDataJSON, `{"_id":"foo"} }`},
+		err:    "invalid character '}' looking for beginning of value",
+		status: chttp.ExitPostError,
+	})
+
+	tests.Run(t, func(t *testing.T, test mddTest) {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		AddFlags(flags)
+		if err := flags.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		flag, err := MultipleDataDocs(flags)
+		testy.ExitStatusError(t, test.err, test.status, err)
+		if flag != test.expected {
+			t.Errorf("Unexpected flag: %s", flag)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package io
 
 import (
 	"github.com/go-kivik/kouch/io/outputjson"
+	"github.com/go-kivik/kouch/io/outputprom"
 	"github.com/go-kivik/kouch/io/outputtmpl"
 	"github.com/go-kivik/kouch/io/outputyaml"
 	"github.com/go-kivik/kouch/kouchio"
@@ -13,4 +14,5 @@ var outputModes = map[string]kouchio.OutputMode{
 	defaultOutputMode: &outputjson.JSONMode{},
 	"yaml":            &outputyaml.YAMLMode{},
 	"template":        &outputtmpl.TmplMode{},
+	"prometheus":      &outputprom.PrometheusMode{},
 }
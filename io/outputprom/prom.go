@@ -0,0 +1,156 @@
+package outputprom
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/io/outputcommon"
+	"github.com/go-kivik/kouch/kouchio"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// PrometheusMode outputs numeric values in the Prometheus text exposition
+// format, as used by the CouchDB _stats and _system endpoints.
+type PrometheusMode struct{}
+
+var _ kouchio.OutputMode = &PrometheusMode{}
+
+// AddFlags adds Prometheus-specific flags.
+func (m *PrometheusMode) AddFlags(flags *pflag.FlagSet) {
+	flags.String(kouch.FlagPrometheusPrefix, "", "Prefix for metric names in Prometheus output.")
+}
+
+// New returns a new Prometheus outputter.
+func (m *PrometheusMode) New(ctx context.Context, w io.Writer) (io.Writer, error) {
+	prefix, err := kouch.Flags(ctx).GetString(kouch.FlagPrometheusPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return outputcommon.NewProcessor(w, func(o io.Writer, i interface{}) error {
+		buf := &bytes.Buffer{}
+		var path []string
+		if prefix != "" {
+			path = []string{prefix}
+		}
+		if err := writeMetrics(buf, path, i); err != nil {
+			return err
+		}
+		_, err := buf.WriteTo(o)
+		return err
+	}), nil
+}
+
+var invalidNameRE = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+
+// metricName joins path into a valid metric name.
+func metricName(path []string) string {
+	name := invalidNameRE.ReplaceAllString(strings.Join(path, "_"), "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// writeMetrics writes the numeric values in v, naming each one by its path.
+// Objects in the format of CouchDB statistics, with `type` and `value`
+// fields, are written with their type and description. Other values, such
+// as strings, are ignored.
+func writeMetrics(w io.Writer, path []string, v interface{}) error {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if isStat(t) {
+			return writeStat(w, path, t)
+		}
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := writeMetrics(w, append(path[:len(path):len(path)], k), t[k]); err != nil {
+				return err
+			}
+		}
+	case float64:
+		name := metricName(path)
+		if name == "" {
+			return errors.New("Prometheus output requires named values")
+		}
+		writeHeader(w, name, "untyped", "")
+		writeSample(w, name, "", t)
+	}
+	return nil
+}
+
+func isStat(stat map[string]interface{}) bool {
+	_, hasValue := stat["value"]
+	switch stat["type"] {
+	case "counter", "gauge", "histogram":
+		return hasValue
+	}
+	return false
+}
+
+func writeStat(w io.Writer, path []string, stat map[string]interface{}) error {
+	name := metricName(path)
+	if name == "" {
+		return errors.New("Prometheus output requires named values")
+	}
+	desc, _ := stat["desc"].(string)
+	if stat["type"] != "histogram" {
+		if value, ok := stat["value"].(float64); ok {
+			writeHeader(w, name, stat["type"].(string), desc)
+			writeSample(w, name, "", value)
+		}
+		return nil
+	}
+	// CouchDB histograms are summaries of recent samples, with percentiles
+	// rather than cumulative buckets, so they are written as summaries.
+	value, ok := stat["value"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	writeHeader(w, name, "summary", desc)
+	percentiles, _ := value["percentile"].([]interface{})
+	for _, p := range percentiles {
+		pair, _ := p.([]interface{})
+		if len(pair) != 2 {
+			continue
+		}
+		percentile, ok1 := pair[0].(float64)
+		v, ok2 := pair[1].(float64)
+		if ok1 && ok2 {
+			writeSample(w, name, `{quantile="`+formatFloat(percentile/100)+`"}`, v)
+		}
+	}
+	n, _ := value["n"].(float64)
+	mean, _ := value["arithmetic_mean"].(float64)
+	writeSample(w, name+"_sum", "", mean*n)
+	writeSample(w, name+"_count", "", n)
+	return nil
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func writeHeader(w io.Writer, name, typ, help string) {
+	if help != "" {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n", name, helpEscaper.Replace(help))
+	}
+	_, _ = fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+func writeSample(w io.Writer, name, labels string, value float64) {
+	_, _ = fmt.Fprintf(w, "%s%s %s\n", name, labels, formatFloat(value))
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,107 @@
+package outputprom
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+	"github.com/go-kivik/kouch/kouchio"
+	"github.com/spf13/cobra"
+)
+
+func TestPrometheusModeConfig(t *testing.T) {
+	cmd := &cobra.Command{}
+	mode := &PrometheusMode{}
+	mode.AddFlags(cmd.PersistentFlags())
+
+	test.Flags(t, []string{"prometheus-prefix"}, cmd)
+}
+
+func TestPrometheusOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		input    string
+		expected string
+		err      string
+	}{
+		{
+			name: "stats",
+			input: `{"couchdb":{
+				"open_databases":{"value":3,"type":"counter","desc":"number of open databases"},
+				"request_time":{"value":{"min":0,"max":20,"arithmetic_mean":4.5,"n":10,"percentile":[[50,3.5],[99,19.25]],"histogram":[[0,10]]},"type":"histogram","desc":"length of a request inside CouchDB without MochiWeb"}
+			},"couch_log":{"level":{"info":{"value":12,"type":"counter","desc":"number of logged info\\messages\n"}}}}`,
+			expected: `# HELP couch_log_level_info number of logged info\\messages\n
+# TYPE couch_log_level_info counter
+couch_log_level_info 12
+# HELP couchdb_open_databases number of open databases
+# TYPE couchdb_open_databases counter
+couchdb_open_databases 3
+# HELP couchdb_request_time length of a request inside CouchDB without MochiWeb
+# TYPE couchdb_request_time summary
+couchdb_request_time{quantile="0.5"} 3.5
+couchdb_request_time{quantile="0.99"} 19.25
+couchdb_request_time_sum 45
+couchdb_request_time_count 10
+`,
+		},
+		{
+			name:  "system",
+			args:  []string{"--prometheus-prefix", "couchdb_erlang"},
+			input: `{"uptime":259,"memory":{"processes":1000000},"message_queues":{"couch_file":{"count":1,"min":0}},"distribution":{},"os_proc_count":0,"ignored":"string"}`,
+			expected: `# TYPE couchdb_erlang_memory_processes untyped
+couchdb_erlang_memory_processes 1e+06
+# TYPE couchdb_erlang_message_queues_couch_file_count untyped
+couchdb_erlang_message_queues_couch_file_count 1
+# TYPE couchdb_erlang_message_queues_couch_file_min untyped
+couchdb_erlang_message_queues_couch_file_min 0
+# TYPE couchdb_erlang_os_proc_count untyped
+couchdb_erlang_os_proc_count 0
+# TYPE couchdb_erlang_uptime untyped
+couchdb_erlang_uptime 259
+`,
+		},
+		{
+			name:     "invalid characters",
+			input:    `{"global_changes":{"1st-listener":{"value":1,"type":"gauge","desc":""}}}`,
+			expected: "# TYPE global_changes_1st_listener gauge\nglobal_changes_1st_listener 1\n",
+		},
+		{
+			name:  "unnamed value",
+			input: "42\n",
+			err:   "Prometheus output requires named values",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			mode := &PrometheusMode{}
+			mode.AddFlags(cmd.PersistentFlags())
+			if err := cmd.ParseFlags(test.args); err != nil {
+				t.Fatal(err)
+			}
+
+			buf := &bytes.Buffer{}
+			ctx := kouch.SetFlags(context.Background(), cmd.Flags())
+			p, err := mode.New(ctx, buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = io.Copy(p, strings.NewReader(test.input))
+			if err == nil {
+				err = kouchio.CloseWriter(p)
+			}
+			testy.Error(t, test.err, err)
+			if d := diff.Text(test.expected, buf.String()); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
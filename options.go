@@ -35,7 +35,8 @@ func (o *Options) Query() *url.Values {
 }
 
 var flagExceptions = map[string]string{
-	"shards": "q",
+	FlagShards:   "q",
+	FlagReplicas: "n",
 }
 
 func param(flagName string) string {
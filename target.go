@@ -23,11 +23,12 @@ const (
 	TargetDatabase
 	TargetDocument
 	TargetAttachment
-	// View
-	// Show
-	// List
-	// Update
-	// Rewrite ??
+	TargetPartition
+	TargetView
+	TargetShow
+	TargetList
+	TargetUpdate
+	TargetRewrite
 	targetLastScope = iota - 1
 )
 
@@ -44,6 +45,18 @@ func TargetScopeName(scope TargetScope) string {
 		return "document"
 	case TargetAttachment:
 		return "attachment"
+	case TargetPartition:
+		return "partition"
+	case TargetView:
+		return "view"
+	case TargetShow:
+		return "show"
+	case TargetList:
+		return "list"
+	case TargetUpdate:
+		return "update"
+	case TargetRewrite:
+		return "rewrite"
 	}
 	return ""
 }
@@ -54,14 +67,27 @@ type Target struct {
 	Root string
 	// Database is the database name.
 	Database string
+	// Partition is the partition name, for partitioned databases.
+	Partition string
 	// DocID is the document ID.
 	Document string
 	// Filename is the attachment filename.
 	Filename string
+	// DesignDoc is the design document name, without the _design/ prefix, for
+	// views and other design document functions.
+	DesignDoc string
+	// View is the view name, for views and lists. For lists, it may be
+	// prefixed by the name of another design document, as {ddoc}/{view}.
+	View string
+	// Function is the show, list or update function name, or the path for a
+	// rewrite.
+	Function string
 	// User is the Auth username
 	User string
 	// Password is the Auth password
 	Password string
+	// Protected is true when the root URL was taken from a protected context.
+	Protected bool
 }
 
 // NewTarget builds a new target from the context and flags.
@@ -85,14 +111,30 @@ func NewTarget(ctx context.Context, scope TargetScope, flags *pflag.FlagSet) (*T
 	if err := t.DatabaseFromFlags(flags); err != nil {
 		return nil, err
 	}
+	if err := t.DesignDocFromFlags(flags); err != nil {
+		return nil, err
+	}
+	if err := t.ViewFromFlags(flags); err != nil {
+		return nil, err
+	}
+	if err := t.PartitionFromFlags(flags); err != nil {
+		return nil, err
+	}
+	if err := validatePartition(t); err != nil {
+		return nil, err
+	}
 
 	if defCtx, err := Conf(ctx).DefaultCtx(); err == nil {
 		if t.Root == "" {
 			t.Root = defCtx.Root
 			t.User = defCtx.User
 			t.Password = defCtx.Password
+			t.Protected = defCtx.Protected
 		}
 	}
+	if !t.Protected {
+		t.Protected = Conf(ctx).ProtectedRoot(t.Root)
+	}
 
 	if err := setFromFlags(&t.User, flags, FlagUser, true); err != nil {
 		return nil, err
@@ -129,6 +171,18 @@ func ParseTarget(scope TargetScope, src string) (*Target, error) {
 		return document(target, src)
 	case TargetAttachment:
 		return attachment(target, src)
+	case TargetPartition:
+		return partition(target, src)
+	case TargetView:
+		return view(target, src)
+	case TargetShow:
+		return show(target, src, "_show")
+	case TargetList:
+		return list(target, src)
+	case TargetUpdate:
+		return show(target, src, "_update")
+	case TargetRewrite:
+		return rewrite(target, src)
 	}
 	return nil, errors.New("invalid scope")
 }
@@ -162,6 +216,124 @@ func attachment(t *Target, src string) (*Target, error) {
 	return document(t, src)
 }
 
+func partition(t *Target, src string) (*Target, error) {
+	src, t.Partition = lastSegment(src)
+	if t.Partition == "" {
+		return nil, errIncompleteURL
+	}
+	return database(t, src)
+}
+
+// designTarget parses a target for a design document function, in the
+// format `{db}/_design/{ddoc}/{section}/...` or the short form
+// `{db}/{ddoc}/...`. A short form with only two segments is relative to the
+// current database. The remaining segments, following the design document
+// name, or the section in the long form, are returned.
+func designTarget(t *Target, src, section string) ([]string, error) {
+	parts := strings.Split(src, "/")
+	for i := 2; i < len(parts); i++ {
+		if parts[i] != section || parts[i-2] != "_design" {
+			continue
+		}
+		if t.DesignDoc = parts[i-1]; t.DesignDoc == "" {
+			return nil, errIncompleteURL
+		}
+		if prefix := strings.Join(parts[:i-2], "/"); prefix != "" {
+			if _, err := database(t, prefix); err != nil {
+				return nil, err
+			}
+		}
+		return parts[i+1:], nil
+	}
+	absolute := len(parts) > 1 && parts[0] == ""
+	if absolute {
+		parts = parts[1:]
+	}
+	var db string
+	switch {
+	case len(parts) == 2 && !absolute:
+		// Relative to the current database
+	case len(parts) >= 2:
+		db, parts = parts[0], parts[1:]
+		if len(parts) > 1 && parts[0] == "_design" {
+			parts = parts[1:]
+		}
+	default:
+		return nil, errIncompleteURL
+	}
+	if t.DesignDoc = parts[0]; t.DesignDoc == "" {
+		return nil, errIncompleteURL
+	}
+	if db != "" {
+		if _, err := database(t, db); err != nil {
+			return nil, err
+		}
+	}
+	return parts[1:], nil
+}
+
+// view parses a view target, in the format `{db}/_design/{ddoc}/_view/{view}`
+// or the short form `{db}/{ddoc}/{view}`.
+func view(t *Target, src string) (*Target, error) {
+	rest, err := designTarget(t, src, "_view")
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 1 || rest[0] == "" {
+		return nil, errIncompleteURL
+	}
+	t.View = rest[0]
+	return t, nil
+}
+
+// show parses a show or update function target, in the format
+// `{db}/_design/{ddoc}/{section}/{func}[/{docid}]` or the short form
+// `{db}/{ddoc}/{func}[/{docid}]`.
+func show(t *Target, src, section string) (*Target, error) {
+	rest, err := designTarget(t, src, section)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) == 0 || rest[0] == "" {
+		return nil, errIncompleteURL
+	}
+	t.Function = rest[0]
+	t.Document = strings.Join(rest[1:], "/")
+	return t, nil
+}
+
+// list parses a list function target, in the format
+// `{db}/_design/{ddoc}/_list/{func}/[{other-ddoc}/]{view}` or the short form
+// `{db}/{ddoc}/{func}/[{other-ddoc}/]{view}`.
+func list(t *Target, src string) (*Target, error) {
+	rest, err := designTarget(t, src, "_list")
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < 2 || len(rest) > 3 {
+		return nil, errIncompleteURL
+	}
+	for _, part := range rest {
+		if part == "" {
+			return nil, errIncompleteURL
+		}
+	}
+	t.Function = rest[0]
+	t.View = strings.Join(rest[1:], "/")
+	return t, nil
+}
+
+// rewrite parses a rewrite target, in the format
+// `{db}/_design/{ddoc}/_rewrite/{path}` or the short form `{db}/{ddoc}/{path}`.
+func rewrite(t *Target, src string) (*Target, error) {
+	rest, err := designTarget(t, src, "_rewrite")
+	if err != nil {
+		return nil, err
+	}
+	t.Function = strings.Join(rest, "/")
+	return t, nil
+}
+
 func lastSegment(src string) (string, string) {
 	parts := strings.Split(src, "/")
 	l := len(parts)
@@ -189,6 +361,35 @@ func chopDocument(src string) (string, string) {
 	return strings.Join(parts[0:l-1], "/"), parts[l-1]
 }
 
+// validatePartition ensures that the partition name is valid, and that a
+// document ID, if any, follows the `partition:docid` convention used by
+// partitioned databases.
+func validatePartition(t *Target) error {
+	if t.Partition == "" {
+		return nil
+	}
+	if strings.HasPrefix(t.Partition, "_") || strings.Contains(t.Partition, ":") {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid partition name '%s'", t.Partition)
+	}
+	if t.Document == "" || strings.HasPrefix(t.Document, "_design/") || strings.HasPrefix(t.Document, "_local/") {
+		return nil
+	}
+	if DocPartition(t.Document) != t.Partition {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "Document ID '%s' must begin with the partition prefix '%s:'", t.Document, t.Partition)
+	}
+	return nil
+}
+
+// DocPartition returns the partition portion of a document ID, in the format
+// `partition:docid`, or "" if the ID contains no partition prefix.
+func DocPartition(docID string) string {
+	parts := strings.SplitN(docID, ":", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0]
+}
+
 // NewClient returns a chttp.Client, connected to the target server
 func (t *Target) NewClient() (*chttp.Client, error) {
 	if t.Root == "" {
@@ -215,6 +416,12 @@ var duplicateConfigErrors = map[string]error{
 		"Must not use --%s and pass document ID as part of the target", FlagDocument),
 	FlagFilename: errors.NewExitError(chttp.ExitFailedToInitialize,
 		"Must not use --%s and pass separate filename", FlagFilename),
+	FlagPartition: errors.NewExitError(chttp.ExitFailedToInitialize,
+		"Must not use --%s and pass partition as part of the target", FlagPartition),
+	FlagDesignDoc: errors.NewExitError(chttp.ExitFailedToInitialize,
+		"Must not use --%s and pass design document as part of the target", FlagDesignDoc),
+	FlagView: errors.NewExitError(chttp.ExitFailedToInitialize,
+		"Must not use --%s and pass view as part of the target", FlagView),
 }
 
 func setFromFlags(target *string, flags *pflag.FlagSet, flagName string, allowOverride bool) error {
@@ -249,3 +456,22 @@ func (t *Target) DatabaseFromFlags(flags *pflag.FlagSet) error {
 func (t *Target) FilenameFromFlags(flags *pflag.FlagSet) error {
 	return setFromFlags(&t.Filename, flags, FlagFilename, false)
 }
+
+// PartitionFromFlags sets t.Partition from the passed flagset.
+func (t *Target) PartitionFromFlags(flags *pflag.FlagSet) error {
+	return setFromFlags(&t.Partition, flags, FlagPartition, false)
+}
+
+// DesignDocFromFlags sets t.DesignDoc from the passed flagset.
+func (t *Target) DesignDocFromFlags(flags *pflag.FlagSet) error {
+	if err := setFromFlags(&t.DesignDoc, flags, FlagDesignDoc, false); err != nil {
+		return err
+	}
+	t.DesignDoc = strings.TrimPrefix(t.DesignDoc, "_design/")
+	return nil
+}
+
+// ViewFromFlags sets t.View from the passed flagset.
+func (t *Target) ViewFromFlags(flags *pflag.FlagSet) error {
+	return setFromFlags(&t.View, flags, FlagView, false)
+}
@@ -37,6 +37,11 @@ func addCommonFlags(flags *pflag.FlagSet) {
 	flags.StringP(FlagRev, FlagShortRev, "", "Retrieves attachment from document of specified revision.")
 }
 
+func addPartitionFlags(flags *pflag.FlagSet) {
+	addCommonFlags(flags)
+	flags.String(FlagPartition, "", "The partition name.")
+}
+
 func TestNewTarget(t *testing.T) {
 	defaultConfig := &Config{
 		DefaultContext: "foo",
@@ -66,6 +71,18 @@ func TestNewTarget(t *testing.T) {
 		conf:     defaultConfig,
 		expected: &Target{Root: "http://localhost/"},
 	})
+	tests.Add("bare url matching protected context", newTargetTest{
+		scope: TargetDatabase,
+		args:  []string{"http://prod.example.com/foo"},
+		conf: &Config{
+			DefaultContext: "foo",
+			Contexts: []NamedContext{
+				{Name: "foo", Context: &Context{Root: "foo.com"}},
+				{Name: "prod", Context: &Context{Root: "http://prod.example.com/", Protected: true}},
+			},
+		},
+		expected: &Target{Root: "http://prod.example.com", Database: "foo", Protected: true},
+	})
 	tests.Add("duplicate filenames", newTargetTest{
 		scope:    TargetAttachment,
 		addFlags: addCommonFlags,
@@ -135,6 +152,33 @@ func TestNewTarget(t *testing.T) {
 		},
 	})
 
+	tests.Add("partitioned doc id", newTargetTest{
+		scope:    TargetDocument,
+		addFlags: addPartitionFlags,
+		conf:     defaultConfig,
+		args:     []string{"foo/bar:123", "--" + FlagPartition, "bar"},
+		expected: &Target{
+			Root:      "foo.com",
+			Database:  "foo",
+			Partition: "bar",
+			Document:  "bar:123",
+		},
+	})
+	tests.Add("doc id missing partition prefix", newTargetTest{
+		scope:    TargetDocument,
+		addFlags: addPartitionFlags,
+		args:     []string{"foo/123", "--" + FlagPartition, "bar"},
+		err:      "Document ID '123' must begin with the partition prefix 'bar:'",
+		status:   chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid partition name", newTargetTest{
+		scope:    TargetDocument,
+		addFlags: addPartitionFlags,
+		args:     []string{"foo/a:b:123", "--" + FlagPartition, "a:b"},
+		err:      "Invalid partition name 'a:b'",
+		status:   chttp.ExitFailedToInitialize,
+	})
+
 	tests.Run(t, func(t *testing.T, test newTargetTest) {
 		cmd := &cobra.Command{}
 		if af := test.addFlags; af != nil {
@@ -419,6 +463,143 @@ func TestParseTarget(t *testing.T) {
 			src:      "dbname/foo:bar@baz/@1:2.txt",
 			expected: &Target{Database: "dbname", Document: "foo:bar@baz", Filename: "@1:2.txt"},
 		},
+		{
+			scope:    TargetPartition,
+			name:     "db and partition",
+			src:      "foo/bar",
+			expected: &Target{Database: "foo", Partition: "bar"},
+		},
+		{
+			scope:    TargetPartition,
+			name:     "full url",
+			src:      "http://localhost:5984/foo/bar",
+			expected: &Target{Root: "http://localhost:5984", Database: "foo", Partition: "bar"},
+		},
+		{
+			scope:  TargetPartition,
+			name:   "url missing partition",
+			src:    "http://localhost:5984/foo/",
+			err:    "incomplete target URL",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			scope:    TargetView,
+			name:     "full path",
+			src:      "foo/_design/bar/_view/baz",
+			expected: &Target{Database: "foo", DesignDoc: "bar", View: "baz"},
+		},
+		{
+			scope:    TargetView,
+			name:     "short form",
+			src:      "foo/bar/baz",
+			expected: &Target{Database: "foo", DesignDoc: "bar", View: "baz"},
+		},
+		{
+			scope:    TargetView,
+			name:     "design prefix without _view",
+			src:      "foo/_design/bar/baz",
+			expected: &Target{Database: "foo", DesignDoc: "bar", View: "baz"},
+		},
+		{
+			scope:    TargetView,
+			name:     "relative",
+			src:      "bar/baz",
+			expected: &Target{DesignDoc: "bar", View: "baz"},
+		},
+		{
+			scope:    TargetView,
+			name:     "relative full path",
+			src:      "_design/bar/_view/baz",
+			expected: &Target{DesignDoc: "bar", View: "baz"},
+		},
+		{
+			scope:    TargetView,
+			name:     "full url",
+			src:      "http://localhost:5984/foo/_design/bar/_view/baz",
+			expected: &Target{Root: "http://localhost:5984", Database: "foo", DesignDoc: "bar", View: "baz"},
+		},
+		{
+			scope:  TargetView,
+			name:   "view only",
+			src:    "baz",
+			err:    "incomplete target URL",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			scope:  TargetView,
+			name:   "_view without _design",
+			src:    "foo/bar/_view/baz",
+			err:    "incomplete target URL",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			scope:    TargetShow,
+			name:     "short form with document",
+			src:      "foo/bar/baz/qux",
+			expected: &Target{Database: "foo", DesignDoc: "bar", Function: "baz", Document: "qux"},
+		},
+		{
+			scope:    TargetShow,
+			name:     "relative",
+			src:      "bar/baz",
+			expected: &Target{DesignDoc: "bar", Function: "baz"},
+		},
+		{
+			scope:    TargetShow,
+			name:     "full url, design document",
+			src:      "http://localhost:5984/foo/_design/bar/_show/baz/_design/qux",
+			expected: &Target{Root: "http://localhost:5984", Database: "foo", DesignDoc: "bar", Function: "baz", Document: "_design/qux"},
+		},
+		{
+			scope:    TargetShow,
+			name:     "short full url",
+			src:      "http://localhost:5984/foo/bar/baz",
+			expected: &Target{Root: "http://localhost:5984", Database: "foo", DesignDoc: "bar", Function: "baz"},
+		},
+		{
+			scope:  TargetShow,
+			name:   "missing function",
+			src:    "http://localhost:5984/foo/bar",
+			err:    "incomplete target URL",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			scope:    TargetList,
+			name:     "short form",
+			src:      "foo/bar/baz/qux",
+			expected: &Target{Database: "foo", DesignDoc: "bar", Function: "baz", View: "qux"},
+		},
+		{
+			scope:    TargetList,
+			name:     "other design doc",
+			src:      "foo/_design/bar/_list/baz/other/qux",
+			expected: &Target{Database: "foo", DesignDoc: "bar", Function: "baz", View: "other/qux"},
+		},
+		{
+			scope:  TargetList,
+			name:   "missing view",
+			src:    "foo/_design/bar/_list/baz",
+			err:    "incomplete target URL",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			scope:    TargetUpdate,
+			name:     "full path",
+			src:      "foo/_design/bar/_update/baz",
+			expected: &Target{Database: "foo", DesignDoc: "bar", Function: "baz"},
+		},
+		{
+			scope:    TargetRewrite,
+			name:     "short form",
+			src:      "foo/bar/some/path",
+			expected: &Target{Database: "foo", DesignDoc: "bar", Function: "some/path"},
+		},
+		{
+			scope:    TargetRewrite,
+			name:     "full path",
+			src:      "foo/_design/bar/_rewrite/",
+			expected: &Target{Database: "foo", DesignDoc: "bar"},
+		},
 		{
 			scope:    TargetAttachment,
 			name:     "odd chars, filename only",
@@ -551,6 +732,23 @@ func TestDocumentFromFlags(t *testing.T) {
 	}
 }
 
+func TestDocPartition(t *testing.T) {
+	tests := map[string]string{
+		"":            "",
+		"foo":         "",
+		"foo:bar":     "foo",
+		"foo:bar:baz": "foo",
+		":bar":        "",
+	}
+	for docID, expected := range tests {
+		t.Run(docID, func(t *testing.T) {
+			if result := DocPartition(docID); result != expected {
+				t.Errorf("Expected '%s', got '%s'", expected, result)
+			}
+		})
+	}
+}
+
 func TestDatabaseFromFlags(t *testing.T) {
 	dbFlagSet := func() *pflag.FlagSet {
 		return flagSet(func(pf *pflag.FlagSet) {